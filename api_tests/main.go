@@ -34,7 +34,7 @@ func (CustomTransport) RoundTrip(r *http.Request) (*http.Response, error) {
 	if err != nil {
 		return nil, err
 	}
-	if response.StatusCode != 200 {
+	if response.StatusCode != 200 && response.StatusCode != 204 {
 		return nil, errors.New(fmt.Sprintf("bad code: %d", response.StatusCode))
 	}
 	return response, nil
@@ -110,6 +110,24 @@ func testAPI() error {
 	accessToken := regResp["token"].(string)
 	initialLicenseKey := regResp["account"].(map[string]interface{})["license"].(string)
 
+	// a second device bound to the same account, to be deleted
+	_, publicKey3 := generateKeyPair()
+	regData.PublicKey = publicKey3.String()
+	var regResp2 map[string]interface{}
+	if _, err := client.New().Post(regPath).BodyJSON(regData).ReceiveSuccess(&regResp2); err != nil {
+		return err
+	}
+	deviceId2 := regResp2["id"].(string)
+	defaultHeaders["Authorization"] = fmt.Sprintf("Bearer %s", regResp2["token"].(string))
+	licenseData := struct {
+		LicenseKey string `json:"license"`
+	}{
+		initialLicenseKey,
+	}
+	if _, err := client.New().Put(path.Join(regPath, deviceId2, "account")).BodyJSON(licenseData).ReceiveSuccess(nil); err != nil {
+		return err
+	}
+
 	defaultHeaders["Authorization"] = fmt.Sprintf("Bearer %s", accessToken)
 
 	var tests = []opticgo.TestDefinition{
@@ -183,6 +201,12 @@ func testAPI() error {
 			fmt.Sprintf("reg/%s", deviceId),
 			"PATCH",
 		},
+		{
+			"delete account device",
+			nil,
+			fmt.Sprintf("reg/%s/account/reg/%s", deviceId, deviceId2),
+			"DELETE",
+		},
 		{
 			"recreate license key",
 			nil,
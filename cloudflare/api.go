@@ -1,11 +1,11 @@
 package cloudflare
 
 import (
+	"context"
 	"crypto/tls"
 	"net/http"
 	"time"
 
-	"github.com/ViRb3/wgcf/v2/config"
 	"github.com/ViRb3/wgcf/v2/openapi"
 	"github.com/ViRb3/wgcf/v2/util"
 	"github.com/ViRb3/wgcf/v2/wireguard"
@@ -34,32 +34,13 @@ var (
 		TLSHandshakeTimeout:   10 * time.Second,
 		ExpectContinueTimeout: 1 * time.Second,
 	}
+	DefaultRetryTransport = NewRetryTransport(DefaultTransport)
 )
 
-var apiClient = MakeApiClient(nil)
-var apiClientAuth *openapi.APIClient
-
-func MakeApiClient(authToken *string) *openapi.APIClient {
-	httpClient := http.Client{Transport: DefaultTransport}
-	apiClient := openapi.NewAPIClient(&openapi.Configuration{
-		DefaultHeader: DefaultHeaders,
-		UserAgent:     DefaultHeaders["User-Agent"],
-		Debug:         false,
-		Servers: []openapi.ServerConfiguration{
-			{URL: ApiUrl},
-		},
-		HTTPClient: &httpClient,
-	})
-	if authToken != nil {
-		apiClient.GetConfig().DefaultHeader["Authorization"] = "Bearer " + *authToken
-	}
-	return apiClient
-}
-
-func Register(publicKey *wireguard.Key, deviceModel string) (openapi.Register200Response, error) {
+func (c *Client) Register(ctx context.Context, publicKey *wireguard.Key, deviceModel string) (openapi.Register200Response, error) {
 	timestamp := util.GetTimestamp()
-	result, _, err := apiClient.DefaultApi.
-		Register(nil, ApiVersion).
+	result, resp, err := c.api.DefaultApi.
+		Register(ctx, c.apiVersion).
 		RegisterRequest(openapi.RegisterRequest{
 			FcmToken:  "", // not empty on actual client
 			InstallId: "", // not empty on actual client
@@ -67,48 +48,77 @@ func Register(publicKey *wireguard.Key, deviceModel string) (openapi.Register200
 			Locale:    "en_US",
 			Model:     deviceModel,
 			Tos:       timestamp,
-			Type:      "Android",
+			Type:      c.deviceType,
 		}).Execute()
-	return result, err
+	return result, newAPIError(resp, err)
+}
+
+type ClientConfig openapi.GetClientConfig200Response
+
+func (c *Client) GetClientConfig(ctx context.Context) (*ClientConfig, error) {
+	result, resp, err := c.api.DefaultApi.
+		GetClientConfig(ctx, c.apiVersion).
+		Execute()
+	if err != nil {
+		return nil, newAPIError(resp, err)
+	}
+	castResult := ClientConfig(result)
+	return &castResult, nil
 }
 
 type Device openapi.UpdateSourceDevice200Response
 
-func GetSourceDevice(ctx *config.Context) (*Device, error) {
-	result, _, err := globalClientAuth(ctx.AccessToken).DefaultApi.
-		GetSourceDevice(nil, ApiVersion, ctx.DeviceId).
+func (c *Client) GetSourceDevice(ctx context.Context) (*Device, error) {
+	result, resp, err := c.api.DefaultApi.
+		GetSourceDevice(ctx, c.apiVersion, c.account.DeviceId).
 		Execute()
 	castResult := Device{}
 	if err := util.Restructure(&result, &castResult); err != nil {
 		return nil, err
 	}
-	return &castResult, err
+	return &castResult, newAPIError(resp, err)
 }
 
-func globalClientAuth(authToken string) *openapi.APIClient {
-	if apiClientAuth == nil {
-		apiClientAuth = MakeApiClient(&authToken)
+func (c *Client) UpdateSourceDeviceKey(ctx context.Context, publicKey *wireguard.Key) (*Device, error) {
+	result, resp, err := c.api.DefaultApi.
+		UpdateSourceDevice(ctx, c.apiVersion, c.account.DeviceId).
+		UpdateSourceDeviceRequest(openapi.UpdateSourceDeviceRequest{Key: publicKey.String()}).
+		Execute()
+	if err != nil {
+		return nil, newAPIError(resp, err)
 	}
-	return apiClientAuth
+	castResult := Device(result)
+	return &castResult, nil
 }
 
 type Account openapi.GetAccount200Response
 
-func GetAccount(ctx *config.Context) (*Account, error) {
-	result, _, err := globalClientAuth(ctx.AccessToken).DefaultApi.
-		GetAccount(nil, ctx.DeviceId, ApiVersion).
+func (c *Client) GetAccount(ctx context.Context) (*Account, error) {
+	result, resp, err := c.api.DefaultApi.
+		GetAccount(ctx, c.account.DeviceId, c.apiVersion).
 		Execute()
 	castResult := Account(result)
-	return &castResult, err
+	return &castResult, newAPIError(resp, err)
 }
 
-func UpdateLicenseKey(ctx *config.Context) (*openapi.UpdateAccount200Response, error) {
-	result, _, err := globalClientAuth(ctx.AccessToken).DefaultApi.
-		UpdateAccount(nil, ctx.DeviceId, ApiVersion).
-		UpdateAccountRequest(openapi.UpdateAccountRequest{License: ctx.LicenseKey}).
+func (c *Client) UpdateLicenseKey(ctx context.Context) (*openapi.UpdateAccount200Response, error) {
+	result, resp, err := c.api.DefaultApi.
+		UpdateAccount(ctx, c.account.DeviceId, c.apiVersion).
+		UpdateAccountRequest(openapi.UpdateAccountRequest{License: c.account.LicenseKey}).
 		Execute()
 	if err != nil {
-		return nil, err
+		return nil, newAPIError(resp, err)
+	}
+
+	return &result, nil
+}
+
+func (c *Client) ResetLicense(ctx context.Context) (*openapi.ResetAccountLicense200Response, error) {
+	result, resp, err := c.api.DefaultApi.
+		ResetAccountLicense(ctx, c.account.DeviceId, c.apiVersion).
+		Execute()
+	if err != nil {
+		return nil, newAPIError(resp, err)
 	}
 
 	return &result, nil
@@ -116,12 +126,12 @@ func UpdateLicenseKey(ctx *config.Context) (*openapi.UpdateAccount200Response, e
 
 type BoundDevice openapi.GetBoundDevices200Response
 
-func GetBoundDevices(ctx *config.Context) ([]BoundDevice, error) {
-	result, _, err := globalClientAuth(ctx.AccessToken).DefaultApi.
-		GetBoundDevices(nil, ctx.DeviceId, ApiVersion).
+func (c *Client) GetBoundDevices(ctx context.Context) ([]BoundDevice, error) {
+	result, resp, err := c.api.DefaultApi.
+		GetBoundDevices(ctx, c.account.DeviceId, c.apiVersion).
 		Execute()
 	if err != nil {
-		return nil, err
+		return nil, newAPIError(resp, err)
 	}
 	var castResult []BoundDevice
 	for _, device := range result {
@@ -130,37 +140,58 @@ func GetBoundDevices(ctx *config.Context) ([]BoundDevice, error) {
 	return castResult, nil
 }
 
-func GetSourceBoundDevice(ctx *config.Context) (*BoundDevice, error) {
-	result, err := GetBoundDevices(ctx)
+func (c *Client) GetSourceBoundDevice(ctx context.Context) (*BoundDevice, error) {
+	result, err := c.GetBoundDevices(ctx)
 	if err != nil {
 		return nil, err
 	}
-	return FindDevice(result, ctx.DeviceId)
+	return FindDevice(result, c.account.DeviceId)
+}
+
+func (c *Client) UpdateSourceBoundDeviceName(ctx context.Context, newName string) (*BoundDevice, error) {
+	return c.UpdateBoundDeviceName(ctx, c.account.DeviceId, newName)
 }
 
-func UpdateSourceBoundDeviceName(ctx *config.Context, newName string) (*BoundDevice, error) {
-	return UpdateSourceBoundDevice(ctx, openapi.UpdateBoundDeviceRequest{
+func (c *Client) UpdateSourceBoundDeviceActive(ctx context.Context, active bool) (*BoundDevice, error) {
+	return c.UpdateBoundDeviceActive(ctx, c.account.DeviceId, active)
+}
+
+func (c *Client) UpdateSourceBoundDevice(ctx context.Context, data openapi.UpdateBoundDeviceRequest) (*BoundDevice, error) {
+	return c.UpdateBoundDevice(ctx, c.account.DeviceId, data)
+}
+
+func (c *Client) UpdateBoundDeviceName(ctx context.Context, boundDeviceId string, newName string) (*BoundDevice, error) {
+	return c.UpdateBoundDevice(ctx, boundDeviceId, openapi.UpdateBoundDeviceRequest{
 		Name: &newName,
 	})
 }
 
-func UpdateSourceBoundDeviceActive(ctx *config.Context, active bool) (*BoundDevice, error) {
-	return UpdateSourceBoundDevice(ctx, openapi.UpdateBoundDeviceRequest{
+func (c *Client) UpdateBoundDeviceActive(ctx context.Context, boundDeviceId string, active bool) (*BoundDevice, error) {
+	return c.UpdateBoundDevice(ctx, boundDeviceId, openapi.UpdateBoundDeviceRequest{
 		Active: &active,
 	})
 }
 
-func UpdateSourceBoundDevice(ctx *config.Context, data openapi.UpdateBoundDeviceRequest) (*BoundDevice, error) {
-	result, _, err := globalClientAuth(ctx.AccessToken).DefaultApi.
-		UpdateBoundDevice(nil, ctx.DeviceId, ApiVersion, ctx.DeviceId).
+// boundDeviceId may be any device bound to the same account as the source device
+func (c *Client) UpdateBoundDevice(ctx context.Context, boundDeviceId string, data openapi.UpdateBoundDeviceRequest) (*BoundDevice, error) {
+	result, resp, err := c.api.DefaultApi.
+		UpdateBoundDevice(ctx, c.account.DeviceId, c.apiVersion, boundDeviceId).
 		UpdateBoundDeviceRequest(data).
 		Execute()
 	if err != nil {
-		return nil, err
+		return nil, newAPIError(resp, err)
 	}
 	var castResult []BoundDevice
 	for _, device := range result {
 		castResult = append(castResult, BoundDevice(device))
 	}
-	return FindDevice(castResult, ctx.DeviceId)
+	return FindDevice(castResult, boundDeviceId)
+}
+
+// boundDeviceId may be any device bound to the same account as the source device
+func (c *Client) DeleteBoundDevice(ctx context.Context, boundDeviceId string) error {
+	resp, err := c.api.DefaultApi.
+		DeleteBoundDevice(ctx, c.account.DeviceId, c.apiVersion, boundDeviceId).
+		Execute()
+	return newAPIError(resp, err)
 }
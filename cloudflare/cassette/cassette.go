@@ -0,0 +1,256 @@
+// Package cassette records API requests and responses into JSONL files, and replays them without network.
+package cassette
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+const Redacted = "REDACTED"
+
+// JSON fields and headers whose values are secret, and never written to a cassette.
+var RedactedFields = []string{"token", "key", "license", "private_key", "fcm_token", "install_id"}
+var RedactedHeaders = []string{"Authorization", "Cookie", "Set-Cookie"}
+
+// A request and its response, stored as one line of a cassette.
+type Interaction struct {
+	Request  Request  `json:"request"`
+	Response Response `json:"response"`
+}
+
+type Request struct {
+	Method string `json:"method"`
+	// path and query, without the host, so cassettes work with any API endpoint
+	Url     string            `json:"url"`
+	Headers map[string]string `json:"headers,omitempty"`
+	Body    string            `json:"body,omitempty"`
+}
+
+type Response struct {
+	StatusCode int               `json:"status_code"`
+	Headers    map[string]string `json:"headers,omitempty"`
+	Body       string            `json:"body,omitempty"`
+}
+
+// Reads all interactions of a cassette file.
+func Load(path string) ([]Interaction, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var interactions []Interaction
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(nil, 16*1024*1024)
+	for scanner.Scan() {
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+		var interaction Interaction
+		if err := json.Unmarshal(scanner.Bytes(), &interaction); err != nil {
+			return nil, errors.WithMessagef(err, "invalid interaction in %s", path)
+		}
+		interactions = append(interactions, interaction)
+	}
+	return interactions, scanner.Err()
+}
+
+func redactHeaders(header http.Header) map[string]string {
+	headers := map[string]string{}
+	for key := range header {
+		headers[key] = header.Get(key)
+	}
+	for _, key := range RedactedHeaders {
+		if _, ok := headers[http.CanonicalHeaderKey(key)]; ok {
+			headers[http.CanonicalHeaderKey(key)] = Redacted
+		}
+	}
+	if len(headers) == 0 {
+		return nil
+	}
+	return headers
+}
+
+// Redacts the secret fields of a JSON body, at any depth. Other bodies are kept as they are.
+func redactBody(body []byte) string {
+	var value interface{}
+	if err := json.Unmarshal(body, &value); err != nil {
+		return string(body)
+	}
+	redacted, err := json.Marshal(redactValue(value))
+	if err != nil {
+		return string(body)
+	}
+	return string(redacted)
+}
+
+func redactValue(value interface{}) interface{} {
+	switch value := value.(type) {
+	case map[string]interface{}:
+		for key, field := range value {
+			if isRedactedField(key) {
+				if _, ok := field.(string); ok {
+					value[key] = Redacted
+				}
+				continue
+			}
+			value[key] = redactValue(field)
+		}
+	case []interface{}:
+		for i := range value {
+			value[i] = redactValue(value[i])
+		}
+	}
+	return value
+}
+
+func isRedactedField(key string) bool {
+	for _, field := range RedactedFields {
+		if key == field {
+			return true
+		}
+	}
+	return false
+}
+
+// Records every request and its response into a cassette file, redacting secrets.
+type Recorder struct {
+	Transport http.RoundTripper
+	Path      string
+	mu        sync.Mutex
+}
+
+// Appends to the cassette file, creating it if needed.
+func NewRecorder(transport http.RoundTripper, path string) *Recorder {
+	return &Recorder{Transport: transport, Path: path}
+}
+
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	var requestBody []byte
+	if req.Body != nil && req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			return nil, err
+		}
+		requestBody, err = io.ReadAll(body)
+		body.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	resp, err := r.Transport.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	responseBody, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(responseBody))
+
+	interaction := Interaction{
+		Request: Request{
+			Method:  req.Method,
+			Url:     req.URL.RequestURI(),
+			Headers: redactHeaders(req.Header),
+			Body:    redactBody(requestBody),
+		},
+		Response: Response{
+			StatusCode: resp.StatusCode,
+			Headers:    redactHeaders(resp.Header),
+			Body:       redactBody(responseBody),
+		},
+	}
+	if err := r.write(interaction); err != nil {
+		return nil, errors.WithMessage(err, "failed to record interaction")
+	}
+	return resp, nil
+}
+
+func (r *Recorder) write(interaction Interaction) error {
+	line, err := json.Marshal(interaction)
+	if err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	file, err := os.OpenFile(r.Path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err := file.Write(append(line, '\n')); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// Replays the interactions of a cassette in order, without network.
+// Each request must match the method and URL of the next interaction.
+type Replayer struct {
+	interactions []Interaction
+	mu           sync.Mutex
+}
+
+func NewReplayer(interactions []Interaction) *Replayer {
+	return &Replayer{interactions: interactions}
+}
+
+func LoadReplayer(path string) (*Replayer, error) {
+	interactions, err := Load(path)
+	if err != nil {
+		return nil, err
+	}
+	return NewReplayer(interactions), nil
+}
+
+// Returns how many interactions haven't been replayed yet.
+func (r *Replayer) Remaining() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.interactions)
+}
+
+func (r *Replayer) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Body != nil {
+		req.Body.Close()
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(r.interactions) == 0 {
+		return nil, errors.Errorf("no interaction left for %s %s", req.Method, req.URL.RequestURI())
+	}
+	interaction := r.interactions[0]
+	if interaction.Request.Method != req.Method || interaction.Request.Url != req.URL.RequestURI() {
+		return nil, errors.Errorf("expected %s %s, got %s %s", interaction.Request.Method, interaction.Request.Url,
+			req.Method, req.URL.RequestURI())
+	}
+	r.interactions = r.interactions[1:]
+
+	header := http.Header{}
+	for key, value := range interaction.Response.Headers {
+		header.Set(key, value)
+	}
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", interaction.Response.StatusCode, http.StatusText(interaction.Response.StatusCode)),
+		StatusCode:    interaction.Response.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewBufferString(interaction.Response.Body)),
+		ContentLength: int64(len(interaction.Response.Body)),
+		Request:       req,
+	}, nil
+}
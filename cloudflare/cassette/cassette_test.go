@@ -0,0 +1,90 @@
+package cassette
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRecordRedactsSecrets(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cassette.jsonl")
+	recorder := NewRecorder(roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Header:     http.Header{"Content-Type": {"application/json"}, "Set-Cookie": {"session=secret-cookie"}},
+			Body: io.NopCloser(strings.NewReader(
+				`{"id":"device","token":"secret-token","account":{"license":"secret-license"},"config":{"peers":[{"public_key":"peer"}]}}`)),
+		}, nil
+	}), path)
+
+	req, err := http.NewRequest(http.MethodPost, "https://api.example.com/v0a1922/reg?x=1", bytes.NewBufferString(`{"key":"secret-key","model":"PC"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Authorization", "Bearer secret-auth")
+	resp, err := (&http.Client{Transport: recorder}).Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(body), "secret-token") {
+		t.Fatal("the response must not be redacted for the caller")
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, secret := range []string{"secret-key", "secret-token", "secret-license", "secret-auth", "secret-cookie"} {
+		if strings.Contains(string(data), secret) {
+			t.Fatalf("cassette contains %s:\n%s", secret, data)
+		}
+	}
+
+	interactions, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(interactions) != 1 || interactions[0].Request.Url != "/v0a1922/reg?x=1" ||
+		!strings.Contains(interactions[0].Response.Body, `"public_key":"peer"`) {
+		t.Fatalf("unexpected interactions %+v", interactions)
+	}
+}
+
+func TestReplayInOrder(t *testing.T) {
+	replayer := NewReplayer([]Interaction{
+		{Request: Request{Method: http.MethodGet, Url: "/a"}, Response: Response{StatusCode: http.StatusOK, Body: "first"}},
+		{Request: Request{Method: http.MethodGet, Url: "/b"}, Response: Response{StatusCode: http.StatusNotFound, Body: "second"}},
+	})
+	client := &http.Client{Transport: replayer}
+
+	if _, err := client.Get("http://any/b"); err == nil {
+		t.Fatal("expected mismatch error")
+	}
+	for _, expected := range []string{"first", "second"} {
+		resp, err := client.Get("http://any/" + map[string]string{"first": "a", "second": "b"}[expected])
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		if string(body) != expected {
+			t.Fatalf("expected %s, got %s", expected, body)
+		}
+	}
+	if _, err := client.Get("http://any/a"); err == nil {
+		t.Fatal("expected no interaction left")
+	}
+}
+
+type roundTripFunc func(req *http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
@@ -0,0 +1,142 @@
+package cloudflare
+
+import (
+	"context"
+	"flag"
+	"net/http"
+	"net/netip"
+	"os"
+	"testing"
+
+	"github.com/ViRb3/wgcf/v2/cloudflare/cassette"
+	"github.com/ViRb3/wgcf/v2/config"
+	"github.com/ViRb3/wgcf/v2/wireguard"
+)
+
+var recordApi = flag.String("record-api", "", "record the session against this API into the cassette, instead of replaying it")
+var recordLicense = flag.String("record-license", "", "Warp+ license key to bind the recorded device to")
+
+const sessionCassette = "testdata/session.jsonl"
+
+// Replays a cassette of a full session, to catch response decoding regressions. To record it again,
+// run the test with -record-api and -record-license, which rewrites the cassette through the same
+// recorder as the --record flag, redacting secrets:
+//
+//	go test ./cloudflare -run TestDecodeRecordedSession -record-api https://api.cloudflareclient.com -record-license <key>
+//
+// The recorded device stays bound to the license's account, delete it afterwards with 'wgcf devices delete'.
+// The license isn't reset during the session, as that would change the key of a real account.
+// The committed cassette was recorded against the cftest mock, as the real API wasn't reachable at the time,
+// so it only proves the client decodes what the mock serves, not what the real API does.
+func TestDecodeRecordedSession(t *testing.T) {
+	options := DefaultClientOptions()
+	licenseKey := cassette.Redacted
+	var replayer *cassette.Replayer
+	if *recordApi != "" {
+		if err := os.Remove(sessionCassette); err != nil && !os.IsNotExist(err) {
+			t.Fatal(err)
+		}
+		options.BaseUrl = *recordApi
+		options.HttpClient = &http.Client{Transport: cassette.NewRecorder(options.HttpClient.Transport, sessionCassette)}
+		licenseKey = *recordLicense
+	} else {
+		var err error
+		if replayer, err = cassette.LoadReplayer(sessionCassette); err != nil {
+			t.Fatal(err)
+		}
+		options.BaseUrl = "http://cassette"
+		options.HttpClient = &http.Client{Transport: replayer}
+	}
+	ctx := context.Background()
+
+	privateKey, err := wireguard.NewPrivateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	device, err := NewClient(options, nil).Register(ctx, privateKey.Public(), "PC")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if device.Id == "" || device.Token == "" || device.Account.AccountType != "free" || device.Config.ClientId == "" {
+		t.Fatalf("unexpected device %+v", device)
+	}
+	if _, err := netip.ParseAddr(device.Config.Interface.Addresses.V4); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := netip.ParseAddr(device.Config.Interface.Addresses.V6); err != nil {
+		t.Fatal(err)
+	}
+
+	client := NewClient(options, &config.Context{DeviceId: device.Id, AccessToken: device.Token, LicenseKey: licenseKey})
+	boundDevice, err := client.UpdateSourceBoundDeviceName(ctx, "wgcf-cassette")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if boundDevice.Name == nil || *boundDevice.Name != "wgcf-cassette" {
+		t.Fatalf("unexpected bound device %+v", boundDevice)
+	}
+
+	thisDevice, err := client.GetSourceDevice(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(thisDevice.Config.Peers) == 0 {
+		t.Fatal("expected a peer")
+	}
+	peer := thisDevice.Config.Peers[0]
+	if _, err := wireguard.NewKey(peer.PublicKey); err != nil {
+		t.Fatal(err)
+	}
+	if peer.Endpoint.Host == "" || peer.Endpoint.V4 == "" || peer.Endpoint.V6 == "" {
+		t.Fatalf("unexpected peer %+v", peer)
+	}
+
+	boundDevice, err = client.UpdateSourceBoundDeviceActive(ctx, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !boundDevice.Active {
+		t.Fatal("expected active device")
+	}
+
+	clientConfig, err := client.GetClientConfig(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := GetExcludedNetworks(clientConfig); err != nil {
+		t.Fatal(err)
+	}
+
+	updatedAccount, err := client.UpdateLicenseKey(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !updatedAccount.WarpPlus {
+		t.Fatalf("unexpected account %+v", updatedAccount)
+	}
+
+	account, err := client.GetAccount(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if account.AccountType == "free" {
+		t.Fatalf("unexpected account %+v", account)
+	}
+
+	boundDevices, err := client.GetBoundDevices(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := FindDevice(boundDevices, device.Id); err != nil || len(boundDevices) < 2 {
+		t.Fatalf("unexpected bound devices %+v", boundDevices)
+	}
+
+	if replayer != nil {
+		if device.Token != cassette.Redacted || account.License != cassette.Redacted {
+			t.Fatal("the cassette contains secrets")
+		}
+		if remaining := replayer.Remaining(); remaining != 0 {
+			t.Fatalf("%d interactions were not replayed", remaining)
+		}
+	}
+}
@@ -0,0 +1,542 @@
+// Package cftest provides an in-memory fake of the Cloudflare Warp API for offline testing.
+package cftest
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ViRb3/wgcf/v2/openapi"
+	"github.com/ViRb3/wgcf/v2/util"
+)
+
+// Maximum number of active devices bound to the same account.
+const MaxActiveDevices = 5
+
+// Error codes returned in the Cloudflare error body, see ErrorBody. These are the mock's own,
+// the real API's codes aren't documented, so the client classifies errors by their message.
+const (
+	ErrorCodeUnauthorized   = 1000
+	ErrorCodeNotFound       = 1001
+	ErrorCodeBadRequest     = 1002
+	ErrorCodeInvalidLicense = 1003
+	ErrorCodeDeviceLimit    = 1004
+)
+
+// Routes which failures can be injected into.
+const (
+	RouteRegister           = "POST /reg"
+	RouteGetSourceDevice    = "GET /reg/{id}"
+	RouteUpdateSourceDevice = "PATCH /reg/{id}"
+	RouteGetAccount         = "GET /reg/{id}/account"
+	RouteUpdateAccount      = "PUT /reg/{id}/account"
+	RouteGetBoundDevices    = "GET /reg/{id}/account/devices"
+	RouteUpdateBoundDevice  = "PATCH /reg/{id}/account/reg/{boundId}"
+	RouteDeleteBoundDevice  = "DELETE /reg/{id}/account/reg/{boundId}"
+	RouteResetLicense       = "POST /reg/{id}/account/license"
+	RouteGetClientConfig    = "GET /client_config"
+)
+
+const (
+	PeerPublicKey = "bmXOC+F1FxEMF9dyiK2H5/1SUtzH0JuVo51h2wPfgyo="
+	EndpointHost  = "engage.cloudflareclient.com:2408"
+	EndpointV4    = "162.159.192.1:0"
+	EndpointV6    = "[2606:4700:d0::a29f:c001]:0"
+)
+
+// A failure returned instead of the normal response of a route.
+type Failure struct {
+	Route      string
+	StatusCode int
+	// defaults to a Cloudflare error body with ErrorCode
+	Body      string
+	ErrorCode int
+	Headers   map[string]string
+	// how many requests fail, 0 means every request
+	Times int
+}
+
+type Account struct {
+	Id          string
+	License     string
+	AccountType string
+	PremiumData float32
+	Quota       float32
+	Created     string
+}
+
+type Device struct {
+	Id        string
+	Token     string
+	Key       string
+	Model     string
+	Name      *string
+	Type      string
+	Locale    string
+	Tos       string
+	Role      string
+	Active    bool
+	Created   string
+	Activated string
+	AccountId string
+	ClientId  string
+	AddressV4 string
+	AddressV6 string
+}
+
+type Server struct {
+	*httptest.Server
+	ClientConfig openapi.GetClientConfig200Response
+
+	mu       sync.Mutex
+	accounts map[string]*Account
+	devices  map[string]*Device
+	// in registration order
+	deviceIds []string
+	failures  []*Failure
+	requests  map[string]int
+}
+
+// Starts a new server, which must be closed after use.
+func NewServer() *Server {
+	s := &Server{
+		ClientConfig: openapi.GetClientConfig200Response{
+			CaptivePortal: []openapi.GetClientConfig200ResponseCaptivePortal{},
+			Denylist:      []openapi.GetClientConfig200ResponseDenylist{},
+		},
+		accounts: map[string]*Account{},
+		devices:  map[string]*Device{},
+		requests: map[string]int{},
+	}
+	mux := http.NewServeMux()
+	s.handle(mux, RouteRegister, false, s.register)
+	s.handle(mux, RouteGetSourceDevice, true, s.getSourceDevice)
+	s.handle(mux, RouteUpdateSourceDevice, true, s.updateSourceDevice)
+	s.handle(mux, RouteGetAccount, true, s.getAccount)
+	s.handle(mux, RouteUpdateAccount, true, s.updateAccount)
+	s.handle(mux, RouteGetBoundDevices, true, s.getBoundDevices)
+	s.handle(mux, RouteUpdateBoundDevice, true, s.updateBoundDevice)
+	s.handle(mux, RouteDeleteBoundDevice, true, s.deleteBoundDevice)
+	s.handle(mux, RouteResetLicense, true, s.resetLicense)
+	s.handle(mux, RouteGetClientConfig, false, s.getClientConfig)
+	s.Server = httptest.NewServer(mux)
+	return s
+}
+
+// Makes the following requests to the route fail.
+func (s *Server) InjectFailure(failure Failure) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.failures = append(s.failures, &failure)
+}
+
+// Returns how many requests were made to the route, including failed ones.
+func (s *Server) Requests(route string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.requests[route]
+}
+
+// Returns a copy of the device, or nil if it doesn't exist.
+func (s *Server) Device(id string) *Device {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if device, ok := s.devices[id]; ok {
+		deviceCopy := *device
+		return &deviceCopy
+	}
+	return nil
+}
+
+// Returns a copy of the account the device is bound to, or nil if the device doesn't exist.
+func (s *Server) AccountOf(deviceId string) *Account {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if device, ok := s.devices[deviceId]; ok {
+		accountCopy := *s.accounts[device.AccountId]
+		return &accountCopy
+	}
+	return nil
+}
+
+// Creates a Warp+ account with the given number of active devices, and returns its license key.
+func (s *Server) CreatePlusAccount(activeDevices int) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	account := s.newAccount()
+	account.AccountType = "unlimited"
+	account.PremiumData = 1 << 40
+	account.Quota = 1 << 40
+	for i := 0; i < activeDevices; i++ {
+		s.newDevice(account, "", "Phone", "Android")
+	}
+	return account.License
+}
+
+type handlerFunc func(w http.ResponseWriter, r *http.Request, device *Device)
+
+// Registers the route under any API version, e.g. /v0a1922/reg.
+func (s *Server) handle(mux *http.ServeMux, route string, authenticated bool, handler handlerFunc) {
+	method, path, _ := strings.Cut(route, " ")
+	mux.HandleFunc(method+" /{apiVersion}"+path, func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.requests[route]++
+
+		if s.injectFailure(w, route) {
+			return
+		}
+		var device *Device
+		if authenticated {
+			device = s.devices[r.PathValue("id")]
+			if device == nil || r.Header.Get("Authorization") != "Bearer "+device.Token {
+				writeError(w, http.StatusUnauthorized, ErrorCodeUnauthorized, "Unauthorized")
+				return
+			}
+		}
+		handler(w, r, device)
+	})
+}
+
+func (s *Server) injectFailure(w http.ResponseWriter, route string) bool {
+	for i, failure := range s.failures {
+		if failure.Route != route {
+			continue
+		}
+		if failure.Times > 0 {
+			failure.Times--
+			if failure.Times == 0 {
+				s.failures = append(s.failures[:i], s.failures[i+1:]...)
+			}
+		}
+		for key, value := range failure.Headers {
+			w.Header().Set(key, value)
+		}
+		if failure.Body != "" {
+			w.WriteHeader(failure.StatusCode)
+			_, _ = w.Write([]byte(failure.Body))
+		} else {
+			writeError(w, failure.StatusCode, failure.ErrorCode, http.StatusText(failure.StatusCode))
+		}
+		return true
+	}
+	return false
+}
+
+type ErrorBody struct {
+	Result   interface{}    `json:"result"`
+	Success  bool           `json:"success"`
+	Errors   []ErrorMessage `json:"errors"`
+	Messages []ErrorMessage `json:"messages"`
+}
+
+type ErrorMessage struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func writeError(w http.ResponseWriter, statusCode int, code int, message string) {
+	writeJson(w, statusCode, ErrorBody{
+		Errors:   []ErrorMessage{{Code: code, Message: message}},
+		Messages: []ErrorMessage{},
+	})
+}
+
+func writeJson(w http.ResponseWriter, statusCode int, value interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	_ = json.NewEncoder(w).Encode(value)
+}
+
+func readJson(w http.ResponseWriter, r *http.Request, value interface{}) bool {
+	if err := json.NewDecoder(r.Body).Decode(value); err != nil {
+		writeError(w, http.StatusBadRequest, ErrorCodeBadRequest, "Invalid request body")
+		return false
+	}
+	return true
+}
+
+func timestamp() string {
+	return time.Now().UTC().Format(time.RFC3339Nano)
+}
+
+func newLicense() string {
+	return fmt.Sprintf("%s-%s-%s", util.RandomHexString(4), util.RandomHexString(4), util.RandomHexString(4))
+}
+
+func (s *Server) newAccount() *Account {
+	account := &Account{
+		Id:          util.RandomHexString(16),
+		License:     newLicense(),
+		AccountType: "free",
+		Created:     timestamp(),
+	}
+	s.accounts[account.Id] = account
+	return account
+}
+
+func (s *Server) newDevice(account *Account, key string, model string, deviceType string) *Device {
+	role := "child"
+	if len(s.boundDevices(account)) == 0 {
+		role = "parent"
+	}
+	index := len(s.deviceIds) + 2
+	device := &Device{
+		Id:        "t." + util.RandomHexString(16),
+		Token:     util.RandomHexString(32),
+		Key:       key,
+		Model:     model,
+		Type:      deviceType,
+		Locale:    "en_US",
+		Role:      role,
+		Active:    true,
+		Created:   timestamp(),
+		AccountId: account.Id,
+		ClientId:  "AQID",
+		AddressV4: fmt.Sprintf("172.16.%d.%d", index/256%256, index%256),
+		AddressV6: fmt.Sprintf("2606:4700:110:8000::%x", index),
+	}
+	device.Activated = device.Created
+	s.devices[device.Id] = device
+	s.deviceIds = append(s.deviceIds, device.Id)
+	return device
+}
+
+func (s *Server) boundDevices(account *Account) []*Device {
+	var result []*Device
+	for _, id := range s.deviceIds {
+		if s.devices[id].AccountId == account.Id {
+			result = append(result, s.devices[id])
+		}
+	}
+	return result
+}
+
+func (s *Server) activeDevices(account *Account) int {
+	count := 0
+	for _, device := range s.boundDevices(account) {
+		if device.Active {
+			count++
+		}
+	}
+	return count
+}
+
+func (s *Server) accountResponse(account *Account, device *Device) openapi.GetSourceDevice200ResponseAccount {
+	return openapi.GetSourceDevice200ResponseAccount{
+		AccountType: account.AccountType,
+		Created:     account.Created,
+		Id:          account.Id,
+		License:     account.License,
+		PremiumData: account.PremiumData,
+		Quota:       account.Quota,
+		Role:        device.Role,
+		Updated:     timestamp(),
+		WarpPlus:    account.AccountType != "free",
+	}
+}
+
+func (s *Server) deviceResponse(device *Device) openapi.GetSourceDevice200Response {
+	name := ""
+	if device.Name != nil {
+		name = *device.Name
+	}
+	return openapi.GetSourceDevice200Response{
+		Account: s.accountResponse(s.accounts[device.AccountId], device),
+		Config: openapi.GetSourceDevice200ResponseConfig{
+			ClientId: device.ClientId,
+			Interface: openapi.GetSourceDevice200ResponseConfigInterface{
+				Addresses: openapi.GetSourceDevice200ResponseConfigInterfaceAddresses{
+					V4: device.AddressV4,
+					V6: device.AddressV6,
+				},
+			},
+			Peers: []openapi.GetSourceDevice200ResponseConfigPeers{{
+				Endpoint: openapi.GetSourceDevice200ResponseConfigEndpoint{
+					Host: EndpointHost,
+					V4:   EndpointV4,
+					V6:   EndpointV6,
+				},
+				PublicKey: PeerPublicKey,
+			}},
+			Services: openapi.GetSourceDevice200ResponseConfigServices{
+				HttpProxy: "172.16.0.1:2480",
+			},
+		},
+		Created:     device.Created,
+		Enabled:     true,
+		Id:          device.Id,
+		Key:         device.Key,
+		Locale:      device.Locale,
+		Model:       device.Model,
+		Name:        name,
+		Tos:         device.Tos,
+		Type:        device.Type,
+		Updated:     timestamp(),
+		WarpEnabled: true,
+	}
+}
+
+func boundDeviceResponse(device *Device) openapi.GetBoundDevices200Response {
+	return openapi.GetBoundDevices200Response{
+		Activated: device.Activated,
+		Active:    device.Active,
+		Created:   device.Created,
+		Id:        device.Id,
+		Model:     device.Model,
+		Name:      device.Name,
+		Role:      device.Role,
+		Type:      device.Type,
+	}
+}
+
+func (s *Server) boundDevicesResponse(account *Account) []openapi.GetBoundDevices200Response {
+	result := []openapi.GetBoundDevices200Response{}
+	for _, device := range s.boundDevices(account) {
+		result = append(result, boundDeviceResponse(device))
+	}
+	return result
+}
+
+func (s *Server) register(w http.ResponseWriter, r *http.Request, _ *Device) {
+	var request openapi.RegisterRequest
+	if !readJson(w, r, &request) {
+		return
+	}
+	if request.Key == "" {
+		writeError(w, http.StatusBadRequest, ErrorCodeBadRequest, "Missing key")
+		return
+	}
+	device := s.newDevice(s.newAccount(), request.Key, request.Model, request.Type)
+	device.Tos = request.Tos
+	device.Locale = request.Locale
+	deviceResponse := s.deviceResponse(device)
+
+	var response openapi.Register200Response
+	if err := util.Restructure(&deviceResponse, &response); err != nil {
+		writeError(w, http.StatusInternalServerError, ErrorCodeBadRequest, err.Error())
+		return
+	}
+	response.Token = device.Token
+	writeJson(w, http.StatusOK, response)
+}
+
+func (s *Server) getSourceDevice(w http.ResponseWriter, _ *http.Request, device *Device) {
+	writeJson(w, http.StatusOK, s.deviceResponse(device))
+}
+
+func (s *Server) updateSourceDevice(w http.ResponseWriter, r *http.Request, device *Device) {
+	var request openapi.UpdateSourceDeviceRequest
+	if !readJson(w, r, &request) {
+		return
+	}
+	if request.Key == "" {
+		writeError(w, http.StatusBadRequest, ErrorCodeBadRequest, "Missing key")
+		return
+	}
+	device.Key = request.Key
+	writeJson(w, http.StatusOK, s.deviceResponse(device))
+}
+
+func (s *Server) getAccount(w http.ResponseWriter, _ *http.Request, device *Device) {
+	writeJson(w, http.StatusOK, s.accountResponse(s.accounts[device.AccountId], device))
+}
+
+func (s *Server) updateAccount(w http.ResponseWriter, r *http.Request, device *Device) {
+	var request openapi.UpdateAccountRequest
+	if !readJson(w, r, &request) {
+		return
+	}
+	var target *Account
+	for _, account := range s.accounts {
+		if account.License == request.License {
+			target = account
+		}
+	}
+	if target == nil {
+		writeError(w, http.StatusBadRequest, ErrorCodeInvalidLicense, "Invalid license")
+		return
+	}
+	if target.Id != device.AccountId {
+		if device.Active && s.activeDevices(target) >= MaxActiveDevices {
+			writeError(w, http.StatusForbidden, ErrorCodeDeviceLimit, "Too many connected devices")
+			return
+		}
+		device.AccountId = target.Id
+		device.Role = "child"
+		// like the real API, changing the account resets the device name
+		device.Name = nil
+	}
+	account := s.accountResponse(target, device)
+	writeJson(w, http.StatusOK, openapi.UpdateAccount200Response{
+		Created:     account.Created,
+		Id:          account.Id,
+		PremiumData: account.PremiumData,
+		Quota:       account.Quota,
+		Role:        account.Role,
+		Updated:     account.Updated,
+		WarpPlus:    account.WarpPlus,
+	})
+}
+
+func (s *Server) getBoundDevices(w http.ResponseWriter, _ *http.Request, device *Device) {
+	writeJson(w, http.StatusOK, s.boundDevicesResponse(s.accounts[device.AccountId]))
+}
+
+func (s *Server) updateBoundDevice(w http.ResponseWriter, r *http.Request, device *Device) {
+	var request openapi.UpdateBoundDeviceRequest
+	if !readJson(w, r, &request) {
+		return
+	}
+	account := s.accounts[device.AccountId]
+	boundDevice := s.devices[r.PathValue("boundId")]
+	if boundDevice == nil || boundDevice.AccountId != account.Id {
+		writeError(w, http.StatusNotFound, ErrorCodeNotFound, "Device not found")
+		return
+	}
+	if request.Active != nil && *request.Active && !boundDevice.Active {
+		if s.activeDevices(account) >= MaxActiveDevices {
+			writeError(w, http.StatusForbidden, ErrorCodeDeviceLimit, "Too many connected devices")
+			return
+		}
+		boundDevice.Activated = timestamp()
+	}
+	if request.Active != nil {
+		boundDevice.Active = *request.Active
+	}
+	if request.Name != nil {
+		name := *request.Name
+		boundDevice.Name = &name
+	}
+	writeJson(w, http.StatusOK, s.boundDevicesResponse(account))
+}
+
+func (s *Server) deleteBoundDevice(w http.ResponseWriter, r *http.Request, device *Device) {
+	boundDevice := s.devices[r.PathValue("boundId")]
+	if boundDevice == nil || boundDevice.AccountId != device.AccountId {
+		writeError(w, http.StatusNotFound, ErrorCodeNotFound, "Device not found")
+		return
+	}
+	delete(s.devices, boundDevice.Id)
+	for i, id := range s.deviceIds {
+		if id == boundDevice.Id {
+			s.deviceIds = append(s.deviceIds[:i], s.deviceIds[i+1:]...)
+			break
+		}
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) resetLicense(w http.ResponseWriter, _ *http.Request, device *Device) {
+	account := s.accounts[device.AccountId]
+	account.License = newLicense()
+	writeJson(w, http.StatusOK, openapi.ResetAccountLicense200Response{License: account.License})
+}
+
+func (s *Server) getClientConfig(w http.ResponseWriter, _ *http.Request, _ *Device) {
+	writeJson(w, http.StatusOK, s.ClientConfig)
+}
@@ -0,0 +1,118 @@
+package cloudflare
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/ViRb3/wgcf/v2/cloudflare/cftest"
+	"github.com/ViRb3/wgcf/v2/config"
+	"github.com/ViRb3/wgcf/v2/wireguard"
+	"github.com/pkg/errors"
+)
+
+func newTestClient(t *testing.T, server *cftest.Server) *Client {
+	options := DefaultClientOptions()
+	options.BaseUrl = server.URL
+	retryTransport := NewRetryTransport(DefaultTransport)
+	retryTransport.BaseDelay = time.Millisecond
+	options.HttpClient = &http.Client{Transport: retryTransport}
+	return NewClient(options, nil)
+}
+
+func registerTestDevice(t *testing.T, client *Client) *Client {
+	privateKey, err := wireguard.NewPrivateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	device, err := client.Register(context.Background(), privateKey.Public(), "PC")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return client.WithAccount(&config.Context{
+		DeviceId:    device.Id,
+		AccessToken: device.Token,
+		PrivateKey:  privateKey.String(),
+		LicenseKey:  device.Account.License,
+	})
+}
+
+func TestMockRegisterAndGetDevice(t *testing.T) {
+	server := cftest.NewServer()
+	defer server.Close()
+	client := registerTestDevice(t, newTestClient(t, server))
+
+	device, err := client.GetSourceDevice(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if device.Config.Peers[0].PublicKey != cftest.PeerPublicKey {
+		t.Fatalf("unexpected peer public key %s", device.Config.Peers[0].PublicKey)
+	}
+	if device.Account.Role != "parent" || device.Account.AccountType != "free" {
+		t.Fatalf("unexpected account %+v", device.Account)
+	}
+
+	unauthorized := client.WithAccount(&config.Context{DeviceId: device.Id, AccessToken: "invalid"})
+	if _, err := unauthorized.GetSourceDevice(context.Background()); !errors.Is(err, ErrUnauthorized) {
+		t.Fatalf("expected unauthorized error, got %v", err)
+	}
+}
+
+func TestMockDeviceLimit(t *testing.T) {
+	server := cftest.NewServer()
+	defer server.Close()
+	client := registerTestDevice(t, newTestClient(t, server))
+
+	license := server.CreatePlusAccount(cftest.MaxActiveDevices)
+	client = client.WithAccount(&config.Context{DeviceId: client.account.DeviceId, AccessToken: client.account.AccessToken, LicenseKey: license})
+	if _, err := client.UpdateLicenseKey(context.Background()); !errors.Is(err, ErrDeviceLimitReached) {
+		t.Fatalf("expected device limit error, got %v", err)
+	}
+
+	// an inactive device can still be bound, but not activated
+	if _, err := client.UpdateSourceBoundDeviceActive(context.Background(), false); err != nil {
+		t.Fatal(err)
+	}
+	result, err := client.UpdateLicenseKey(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !result.WarpPlus || result.Role != "child" {
+		t.Fatalf("unexpected account %+v", result)
+	}
+	if _, err := client.UpdateSourceBoundDeviceActive(context.Background(), true); !errors.Is(err, ErrDeviceLimitReached) {
+		t.Fatalf("expected device limit error, got %v", err)
+	}
+	boundDevices, err := client.GetBoundDevices(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(boundDevices) != cftest.MaxActiveDevices+1 {
+		t.Fatalf("expected %d bound devices, got %d", cftest.MaxActiveDevices+1, len(boundDevices))
+	}
+}
+
+func TestMockInjectedFailureIsRetried(t *testing.T) {
+	server := cftest.NewServer()
+	defer server.Close()
+	client := registerTestDevice(t, newTestClient(t, server))
+
+	server.InjectFailure(cftest.Failure{Route: cftest.RouteGetAccount, StatusCode: http.StatusServiceUnavailable, Times: 2})
+	if _, err := client.GetAccount(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if requests := server.Requests(cftest.RouteGetAccount); requests != 3 {
+		t.Fatalf("expected 3 requests, got %d", requests)
+	}
+
+	server.InjectFailure(cftest.Failure{Route: cftest.RouteResetLicense, StatusCode: http.StatusInternalServerError})
+	if _, err := client.ResetLicense(context.Background()); err == nil {
+		t.Fatal("expected error")
+	}
+	// POST is not idempotent, so it must not be retried
+	if requests := server.Requests(cftest.RouteResetLicense); requests != 1 {
+		t.Fatalf("expected 1 request, got %d", requests)
+	}
+}
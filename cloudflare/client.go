@@ -0,0 +1,70 @@
+package cloudflare
+
+import (
+	"net/http"
+
+	"github.com/ViRb3/wgcf/v2/config"
+	"github.com/ViRb3/wgcf/v2/openapi"
+)
+
+type ClientOptions struct {
+	BaseUrl    string
+	ApiVersion string
+	Headers    map[string]string
+	// sent when registering a device
+	DeviceType string
+	HttpClient *http.Client
+}
+
+func DefaultClientOptions() ClientOptions {
+	return ClientOptions{
+		BaseUrl:    ApiUrl,
+		ApiVersion: ApiVersion,
+		Headers:    DefaultHeaders,
+		DeviceType: ClientIdentities[DefaultClientIdentity].DeviceType,
+		HttpClient: &http.Client{Transport: DefaultRetryTransport},
+	}
+}
+
+// A Cloudflare Warp API client, authenticated as a single account.
+// A Client is immutable, so it's safe for concurrent use. To use multiple accounts,
+// create a Client for each one, e.g. with WithAccount, which shares the HTTP client.
+type Client struct {
+	options    ClientOptions
+	api        *openapi.APIClient
+	apiVersion string
+	deviceType string
+	account    config.Context
+}
+
+// The account may be nil for unauthenticated requests, e.g. Register.
+func NewClient(options ClientOptions, account *config.Context) *Client {
+	headers := make(map[string]string, len(options.Headers)+1)
+	for key, value := range options.Headers {
+		headers[key] = value
+	}
+	client := Client{
+		options:    options,
+		apiVersion: options.ApiVersion,
+		deviceType: options.DeviceType,
+	}
+	if account != nil {
+		client.account = *account
+		headers["Authorization"] = "Bearer " + account.AccessToken
+	}
+	client.api = openapi.NewAPIClient(&openapi.Configuration{
+		DefaultHeader: headers,
+		UserAgent:     headers["User-Agent"],
+		Debug:         false,
+		Servers: []openapi.ServerConfiguration{
+			{URL: options.BaseUrl},
+		},
+		HTTPClient: options.HttpClient,
+	})
+	return &client
+}
+
+// Returns a new Client with the same options, authenticated as the given account.
+func (c *Client) WithAccount(account *config.Context) *Client {
+	return NewClient(c.options, account)
+}
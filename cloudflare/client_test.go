@@ -0,0 +1,55 @@
+package cloudflare
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/ViRb3/wgcf/v2/config"
+)
+
+func TestClientsUseTheirOwnToken(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// /v0a1922/reg/<device id>/account
+		deviceId := strings.Split(r.URL.Path, "/")[3]
+		if r.Header.Get("Authorization") != "Bearer token-"+deviceId {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"id":"` + deviceId + `","account_type":"free","license":"license-` + deviceId + `"}`))
+	}))
+	defer server.Close()
+
+	options := DefaultClientOptions()
+	options.BaseUrl = server.URL
+	unauthenticated := NewClient(options, nil)
+
+	var wg sync.WaitGroup
+	for _, deviceId := range []string{"a", "b", "c", "d"} {
+		client := unauthenticated.WithAccount(&config.Context{DeviceId: deviceId, AccessToken: "token-" + deviceId})
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 10; i++ {
+				account, err := client.GetAccount(context.Background())
+				if err != nil {
+					t.Errorf("GetAccount error: %v", err)
+					return
+				}
+				if account.License != "license-"+deviceId {
+					t.Errorf("expected license of %s, got %s", deviceId, account.License)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	if _, ok := DefaultHeaders["Authorization"]; ok {
+		t.Fatal("default headers were modified")
+	}
+}
@@ -0,0 +1,145 @@
+package cloudflare
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/ViRb3/wgcf/v2/openapi"
+	"github.com/pkg/errors"
+)
+
+// Categories of API errors, to be checked with errors.Is.
+var (
+	ErrUnauthorized       = errors.New("unauthorized")
+	ErrDeviceLimitReached = errors.New("device limit reached")
+	ErrInvalidLicense     = errors.New("invalid license key")
+	ErrBlockedByWAF       = errors.New("blocked by Cloudflare")
+	ErrRateLimited        = errors.New("rate limited")
+)
+
+var errorHints = map[error]string{
+	ErrUnauthorized: "The access token was rejected, the device may have been deleted. " +
+		"Check the configuration file, or register a new account.",
+	ErrDeviceLimitReached: "Up to 5 devices can be active on the same account. " +
+		"Deactivate one with 'wgcf devices deactivate <id>', then try again.",
+	ErrInvalidLicense: "Check the license key in the configuration file, it may have been reset or mistyped.",
+	ErrBlockedByWAF: "Cloudflare's firewall rejected the request, usually due to the network or the client fingerprint. " +
+		"Try another network, or another --client-identity.",
+	ErrRateLimited: "Too many requests were made, wait a while before trying again.",
+}
+
+// Cloudflare edge error codes, returned as a page instead of an API response.
+var (
+	edgeErrorPattern      = regexp.MustCompile(`(?i)error(?: code)?:?\s*(1\d{3})\b`)
+	edgeBlockedErrorCodes = []int{1006, 1007, 1008, 1009, 1010, 1012, 1020}
+	edgeRateLimitCode     = 1015
+)
+
+// An error returned by the Cloudflare Warp API, with its error code decoded when present.
+type APIError struct {
+	StatusCode int
+	// the first Cloudflare error code, or 0 if none
+	Code    int
+	Message string
+	// one of the error categories, or nil if unknown
+	Kind error
+}
+
+func (e *APIError) Error() string {
+	message := fmt.Sprintf("%d %s", e.StatusCode, http.StatusText(e.StatusCode))
+	if e.Kind != nil {
+		message += ": " + e.Kind.Error()
+	}
+	if e.Message != "" {
+		message += ": " + e.Message
+	}
+	if e.Code != 0 {
+		message += fmt.Sprintf(" (code %d)", e.Code)
+	}
+	return message
+}
+
+func (e *APIError) Unwrap() error {
+	return e.Kind
+}
+
+// Returns an actionable hint for the user, or "" if none.
+func (e *APIError) Hint() string {
+	return errorHints[e.Kind]
+}
+
+// Returns the hint of an API error anywhere in the chain, or "" if none.
+func GetErrorHint(err error) string {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.Hint()
+	}
+	return ""
+}
+
+type errorBody struct {
+	Errors []struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+	} `json:"errors"`
+}
+
+// Converts an error of the generated client into an APIError, if the API responded.
+func newAPIError(resp *http.Response, err error) error {
+	if err == nil {
+		return nil
+	}
+	var openapiErr openapi.GenericOpenAPIError
+	if resp == nil || resp.StatusCode < 400 || !errors.As(err, &openapiErr) {
+		return err
+	}
+	apiErr := &APIError{StatusCode: resp.StatusCode}
+	decodeErrorBody(apiErr, openapiErr.Body())
+	apiErr.Kind = classifyError(apiErr)
+	return apiErr
+}
+
+func decodeErrorBody(apiErr *APIError, body []byte) {
+	var decoded errorBody
+	if err := json.Unmarshal(body, &decoded); err == nil {
+		if len(decoded.Errors) > 0 {
+			apiErr.Code = decoded.Errors[0].Code
+			apiErr.Message = decoded.Errors[0].Message
+		}
+		return
+	}
+	if match := edgeErrorPattern.FindSubmatch(body); match != nil {
+		apiErr.Code, _ = strconv.Atoi(string(match[1]))
+	}
+}
+
+func classifyError(apiErr *APIError) error {
+	message := strings.ToLower(apiErr.Message)
+	switch {
+	case apiErr.Code == edgeRateLimitCode || apiErr.StatusCode == http.StatusTooManyRequests:
+		return ErrRateLimited
+	case isEdgeBlockedCode(apiErr.Code):
+		return ErrBlockedByWAF
+	// the Warp API error codes aren't documented, so only the message is matched
+	case strings.Contains(message, "too many") && strings.Contains(message, "device"):
+		return ErrDeviceLimitReached
+	case strings.Contains(message, "license"):
+		return ErrInvalidLicense
+	case apiErr.StatusCode == http.StatusUnauthorized:
+		return ErrUnauthorized
+	}
+	return nil
+}
+
+func isEdgeBlockedCode(code int) bool {
+	for _, blockedCode := range edgeBlockedErrorCodes {
+		if code == blockedCode {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,75 @@
+package cloudflare
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ViRb3/wgcf/v2/config"
+	"github.com/pkg/errors"
+)
+
+func TestAPIErrorClassification(t *testing.T) {
+	tests := []struct {
+		name       string
+		statusCode int
+		body       string
+		kind       error
+		code       int
+	}{
+		{"unauthorized", http.StatusUnauthorized, `{"success":false,"errors":[{"code":1000,"message":"Unauthorized"}],"result":null}`, ErrUnauthorized, 1000},
+		{"device limit", http.StatusForbidden, `{"success":false,"errors":[{"code":1004,"message":"Too many connected devices."}]}`, ErrDeviceLimitReached, 1004},
+		{"invalid license", http.StatusBadRequest, `{"success":false,"errors":[{"code":1003,"message":"Invalid license"}]}`, ErrInvalidLicense, 1003},
+		{"device limit with an unknown code", http.StatusForbidden, `{"success":false,"errors":[{"code":1234,"message":"Too many connected devices."}]}`, ErrDeviceLimitReached, 1234},
+		{"invalid license with an unknown code", http.StatusBadRequest, `{"success":false,"errors":[{"code":1234,"message":"Invalid license"}]}`, ErrInvalidLicense, 1234},
+		{"device limit by message", http.StatusForbidden, `{"success":false,"errors":[{"message":"Too many connected devices."}]}`, ErrDeviceLimitReached, 0},
+		{"invalid license by message", http.StatusBadRequest, `{"success":false,"errors":[{"message":"Invalid license"}]}`, ErrInvalidLicense, 0},
+		{"edge 1020", http.StatusForbidden, "error code: 1020", ErrBlockedByWAF, 1020},
+		{"edge 1020 html", http.StatusForbidden, "<html><title>Access denied | api.cloudflareclient.com used Cloudflare to restrict access</title><span>Error 1020</span></html>", ErrBlockedByWAF, 1020},
+		{"edge 1015", http.StatusTooManyRequests, "error code: 1015", ErrRateLimited, 1015},
+		{"too many requests", http.StatusTooManyRequests, "", ErrRateLimited, 0},
+		{"unknown", http.StatusInternalServerError, `{"success":false,"errors":[]}`, nil, 0},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(test.statusCode)
+				_, _ = w.Write([]byte(test.body))
+			}))
+			defer server.Close()
+			options := DefaultClientOptions()
+			options.BaseUrl = server.URL
+			options.HttpClient = &http.Client{}
+			_, err := NewClient(options, &config.Context{DeviceId: "device"}).GetAccount(context.Background())
+
+			var apiErr *APIError
+			if !errors.As(err, &apiErr) {
+				t.Fatalf("expected APIError, got %v", err)
+			}
+			if apiErr.StatusCode != test.statusCode || apiErr.Code != test.code {
+				t.Fatalf("unexpected error %+v", apiErr)
+			}
+			if test.kind == nil {
+				if apiErr.Kind != nil || GetErrorHint(err) != "" {
+					t.Fatalf("expected unknown error, got %v", apiErr.Kind)
+				}
+				return
+			}
+			if !errors.Is(err, test.kind) || GetErrorHint(err) == "" {
+				t.Fatalf("expected %v with a hint, got %v", test.kind, err)
+			}
+		})
+	}
+}
+
+func TestNetworkErrorIsNotAPIError(t *testing.T) {
+	options := DefaultClientOptions()
+	options.BaseUrl = "http://127.0.0.1:1"
+	options.HttpClient = &http.Client{}
+	_, err := NewClient(options, nil).GetClientConfig(context.Background())
+	var apiErr *APIError
+	if err == nil || errors.As(err, &apiErr) {
+		t.Fatalf("expected network error, got %v", err)
+	}
+}
@@ -0,0 +1,82 @@
+package cloudflare
+
+import (
+	"crypto/tls"
+	"net/http"
+	"sort"
+)
+
+// How the client presents itself to the API. The API rejects requests whose headers,
+// device type and TLS parameters don't match those of an official client.
+type ClientIdentity struct {
+	UserAgent     string
+	ClientVersion string
+	// sent when registering a device
+	DeviceType    string
+	TLSMinVersion uint16
+	TLSMaxVersion uint16
+}
+
+const DefaultClientIdentity = "android"
+
+var ClientIdentities = map[string]ClientIdentity{
+	"android": {
+		UserAgent:     DefaultHeaders["User-Agent"],
+		ClientVersion: DefaultHeaders["CF-Client-Version"],
+		DeviceType:    "Android",
+		TLSMinVersion: tls.VersionTLS12,
+		TLSMaxVersion: tls.VersionTLS12,
+	},
+	"windows": {
+		UserAgent:     "1.1.1.1/2024.6.473.0",
+		ClientVersion: "w-2024.6.473.0",
+		DeviceType:    "windows",
+		TLSMinVersion: tls.VersionTLS12,
+		TLSMaxVersion: tls.VersionTLS13,
+	},
+	"linux": {
+		UserAgent:     "1.1.1.1/2024.6.497.0",
+		ClientVersion: "l-2024.6.497.0",
+		DeviceType:    "linux",
+		TLSMinVersion: tls.VersionTLS12,
+		TLSMaxVersion: tls.VersionTLS13,
+	},
+}
+
+func GetClientIdentityNames() []string {
+	var names []string
+	for name := range ClientIdentities {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (i ClientIdentity) Headers() map[string]string {
+	return map[string]string{
+		"User-Agent":        i.UserAgent,
+		"CF-Client-Version": i.ClientVersion,
+	}
+}
+
+// Returns a copy of DefaultTransport with the TLS constraints of the identity.
+func (i ClientIdentity) NewTransport() *http.Transport {
+	transport := DefaultTransport.Clone()
+	transport.TLSClientConfig.MinVersion = i.TLSMinVersion
+	transport.TLSClientConfig.MaxVersion = i.TLSMaxVersion
+	return transport
+}
+
+// Client options for the default API, presenting as the given identity.
+// Requests are retried with the same settings as DefaultRetryTransport.
+func NewClientOptions(identity ClientIdentity) ClientOptions {
+	retryTransport := *DefaultRetryTransport
+	retryTransport.Transport = identity.NewTransport()
+	return ClientOptions{
+		BaseUrl:    ApiUrl,
+		ApiVersion: ApiVersion,
+		Headers:    identity.Headers(),
+		DeviceType: identity.DeviceType,
+		HttpClient: &http.Client{Transport: &retryTransport},
+	}
+}
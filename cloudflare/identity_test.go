@@ -0,0 +1,56 @@
+package cloudflare
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ViRb3/wgcf/v2/openapi"
+	"github.com/ViRb3/wgcf/v2/wireguard"
+)
+
+func TestClientIdentityIsSent(t *testing.T) {
+	identity := ClientIdentities["linux"]
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/reg" {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+		if r.Header.Get("User-Agent") != identity.UserAgent || r.Header.Get("CF-Client-Version") != identity.ClientVersion {
+			t.Errorf("unexpected headers %v", r.Header)
+		}
+		var request openapi.RegisterRequest
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			t.Error(err)
+		}
+		if request.Type != identity.DeviceType {
+			t.Errorf("expected device type %s, got %s", identity.DeviceType, request.Type)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"id":"id"}`))
+	}))
+	defer server.Close()
+
+	options := NewClientOptions(identity)
+	options.BaseUrl = server.URL
+	options.ApiVersion = "v1"
+	privateKey, err := wireguard.NewPrivateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewClient(options, nil).Register(context.Background(), privateKey.Public(), "PC"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestClientIdentityTransport(t *testing.T) {
+	transport := ClientIdentities["windows"].NewTransport()
+	if transport.TLSClientConfig.MaxVersion != tls.VersionTLS13 {
+		t.Error("identity TLS version not applied")
+	}
+	if DefaultTransport.TLSClientConfig.MaxVersion != tls.VersionTLS12 {
+		t.Error("default transport was modified")
+	}
+}
@@ -0,0 +1,138 @@
+package cloudflare
+
+import (
+	"io"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Retries requests which failed due to transient errors, with exponential backoff and jitter.
+// Requests with non-idempotent methods (e.g. registering a device) are only retried when the
+// server is known to have rejected them without processing, i.e. on 429 Too Many Requests.
+type RetryTransport struct {
+	Transport http.RoundTripper
+	// including the first attempt, values below 1 are treated as 1
+	MaxAttempts int
+	BaseDelay   time.Duration
+	// also caps Retry-After
+	MaxDelay time.Duration
+}
+
+func NewRetryTransport(transport http.RoundTripper) *RetryTransport {
+	return &RetryTransport{
+		Transport:   transport,
+		MaxAttempts: 3,
+		BaseDelay:   500 * time.Millisecond,
+		MaxDelay:    30 * time.Second,
+	}
+}
+
+func (t *RetryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	for attempt := 1; ; attempt++ {
+		attemptReq := req
+		if attempt > 1 && req.Body != nil {
+			if req.GetBody == nil {
+				// the body can't be rewound, so it can't be sent again
+				return nil, errNoRetryBody
+			}
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			attemptReq = req.Clone(req.Context())
+			attemptReq.Body = body
+		}
+
+		response, err := t.Transport.RoundTrip(attemptReq)
+		if attempt >= t.MaxAttempts || !t.shouldRetry(req, response, err) {
+			return response, err
+		}
+
+		delay := t.backoff(attempt)
+		if response != nil {
+			if retryAfter, ok := parseRetryAfter(response.Header.Get("Retry-After"), time.Now()); ok {
+				delay = min(retryAfter, t.MaxDelay)
+			}
+			// drain so the connection can be reused
+			_, _ = io.Copy(io.Discard, response.Body)
+			_ = response.Body.Close()
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-req.Context().Done():
+			timer.Stop()
+			return nil, req.Context().Err()
+		case <-timer.C:
+		}
+	}
+}
+
+var errNoRetryBody = errors.New("request body can't be sent again")
+
+func (t *RetryTransport) shouldRetry(req *http.Request, response *http.Response, err error) bool {
+	if req.Context().Err() != nil {
+		return false
+	}
+	if response != nil && response.StatusCode == http.StatusTooManyRequests {
+		return true
+	}
+	if !isIdempotent(req.Method) {
+		return false
+	}
+	if err != nil {
+		return true
+	}
+	switch response.StatusCode {
+	case http.StatusInternalServerError, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// PATCH is included, as the API only uses it to set absolute values
+func isIdempotent(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodPatch, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+// full jitter over the upper half of the exponential delay
+func (t *RetryTransport) backoff(attempt int) time.Duration {
+	delay := t.BaseDelay << (attempt - 1)
+	if delay > t.MaxDelay || delay <= 0 {
+		delay = t.MaxDelay
+	}
+	half := int64(delay / 2)
+	if half <= 0 {
+		return delay
+	}
+	return time.Duration(half + rand.Int63n(half+1))
+}
+
+// Supports both delay-seconds and HTTP-date values.
+func parseRetryAfter(value string, now time.Time) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0, false
+		}
+		return time.Duration(seconds) * time.Second, true
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		delay := date.Sub(now)
+		if delay < 0 {
+			delay = 0
+		}
+		return delay, true
+	}
+	return 0, false
+}
@@ -0,0 +1,139 @@
+package cloudflare
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func newTestRetryTransport() *RetryTransport {
+	transport := NewRetryTransport(http.DefaultTransport)
+	transport.BaseDelay = time.Millisecond
+	transport.MaxDelay = 10 * time.Millisecond
+	return transport
+}
+
+// responds with the given status codes in order, then 200
+func newStatusServer(t *testing.T, attempts *int32, statusCodes ...int) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if r.Method == http.MethodPost && string(body) != "body" {
+			t.Errorf("unexpected body %q", body)
+		}
+		attempt := int(atomic.AddInt32(attempts, 1))
+		if attempt <= len(statusCodes) {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(statusCodes[attempt-1])
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func doRequest(t *testing.T, transport http.RoundTripper, method string, url string) *http.Response {
+	var body io.Reader
+	if method == http.MethodPost {
+		body = strings.NewReader("body")
+	}
+	req, err := http.NewRequest(method, url, body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	response, err := (&http.Client{Transport: transport}).Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = response.Body.Close()
+	return response
+}
+
+func TestRetryTransportRetriesTransientErrors(t *testing.T) {
+	var attempts int32
+	server := newStatusServer(t, &attempts, http.StatusServiceUnavailable, http.StatusBadGateway)
+	response := doRequest(t, newTestRetryTransport(), http.MethodGet, server.URL)
+	if response.StatusCode != http.StatusOK || attempts != 3 {
+		t.Fatalf("expected 200 after 3 attempts, got %d after %d", response.StatusCode, attempts)
+	}
+}
+
+func TestRetryTransportGivesUp(t *testing.T) {
+	var attempts int32
+	server := newStatusServer(t, &attempts, http.StatusInternalServerError, http.StatusInternalServerError, http.StatusInternalServerError)
+	transport := newTestRetryTransport()
+	transport.MaxAttempts = 2
+	response := doRequest(t, transport, http.MethodGet, server.URL)
+	if response.StatusCode != http.StatusInternalServerError || attempts != 2 {
+		t.Fatalf("expected 500 after 2 attempts, got %d after %d", response.StatusCode, attempts)
+	}
+}
+
+func TestRetryTransportDoesNotRetryPost(t *testing.T) {
+	var attempts int32
+	server := newStatusServer(t, &attempts, http.StatusInternalServerError)
+	response := doRequest(t, newTestRetryTransport(), http.MethodPost, server.URL)
+	if response.StatusCode != http.StatusInternalServerError || attempts != 1 {
+		t.Fatalf("expected 500 after 1 attempt, got %d after %d", response.StatusCode, attempts)
+	}
+}
+
+func TestRetryTransportRetriesRateLimitedPost(t *testing.T) {
+	var attempts int32
+	server := newStatusServer(t, &attempts, http.StatusTooManyRequests)
+	response := doRequest(t, newTestRetryTransport(), http.MethodPost, server.URL)
+	if response.StatusCode != http.StatusOK || attempts != 2 {
+		t.Fatalf("expected 200 after 2 attempts, got %d after %d", response.StatusCode, attempts)
+	}
+}
+
+func TestRetryTransportDoesNotRetryClientErrors(t *testing.T) {
+	var attempts int32
+	server := newStatusServer(t, &attempts, http.StatusForbidden)
+	response := doRequest(t, newTestRetryTransport(), http.MethodGet, server.URL)
+	if response.StatusCode != http.StatusForbidden || attempts != 1 {
+		t.Fatalf("expected 403 after 1 attempt, got %d after %d", response.StatusCode, attempts)
+	}
+}
+
+func TestRetryTransportStopsOnCancel(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "60")
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+	transport := newTestRetryTransport()
+	transport.MaxDelay = time.Minute
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, server.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.Now()
+	if _, err := transport.RoundTrip(req); err == nil {
+		t.Fatal("expected error")
+	}
+	if time.Since(start) > 5*time.Second {
+		t.Fatal("Retry-After was not interrupted by cancellation")
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2020, 4, 11, 16, 37, 0, 0, time.UTC)
+	if delay, ok := parseRetryAfter("120", now); !ok || delay != 2*time.Minute {
+		t.Errorf("unexpected seconds result %v %t", delay, ok)
+	}
+	if delay, ok := parseRetryAfter("Sat, 11 Apr 2020 16:37:30 GMT", now); !ok || delay != 30*time.Second {
+		t.Errorf("unexpected date result %v %t", delay, ok)
+	}
+	if _, ok := parseRetryAfter("soon", now); ok {
+		t.Error("expected invalid value to be rejected")
+	}
+}
@@ -1,6 +1,11 @@
 package cloudflare
 
 import (
+	"fmt"
+	"net"
+	"net/netip"
+
+	"github.com/ViRb3/wgcf/v2/wireguard"
 	"github.com/pkg/errors"
 )
 
@@ -12,3 +17,46 @@ func FindDevice(devices []BoundDevice, deviceId string) (*BoundDevice, error) {
 	}
 	return nil, errors.New("device not found in list")
 }
+
+// Collects the denylisted and captive portal networks which clients are expected to route outside the tunnel.
+func GetExcludedNetworks(clientConfig *ClientConfig) ([]netip.Prefix, error) {
+	var result []netip.Prefix
+	for _, entry := range clientConfig.Denylist {
+		if entry.Networks == nil {
+			continue
+		}
+		for _, network := range entry.Networks.V4 {
+			addr, err := netip.ParseAddr(network.Address)
+			if err != nil {
+				return nil, errors.WithMessage(err, "denylist "+entry.Name)
+			}
+			mask := net.ParseIP(network.Netmask).To4()
+			if mask == nil {
+				return nil, errors.New(fmt.Sprintf("denylist %s: invalid netmask %s", entry.Name, network.Netmask))
+			}
+			// a non-canonical mask, e.g. 255.0.255.0, would otherwise exclude everything as /0
+			bits, total := net.IPMask(mask).Size()
+			if total == 0 {
+				return nil, errors.New(fmt.Sprintf("denylist %s: invalid netmask %s", entry.Name, network.Netmask))
+			}
+			result = append(result, netip.PrefixFrom(addr, bits).Masked())
+		}
+		for _, network := range entry.Networks.V6 {
+			addr, err := netip.ParseAddr(network.Address)
+			if err != nil {
+				return nil, errors.WithMessage(err, "denylist "+entry.Name)
+			}
+			result = append(result, netip.PrefixFrom(addr, int(network.Prefix)).Masked())
+		}
+	}
+	for _, portal := range clientConfig.CaptivePortal {
+		for _, network := range portal.Networks {
+			prefix, err := wireguard.ParsePrefix(network.Address)
+			if err != nil {
+				return nil, errors.WithMessage(err, "captive portal "+portal.Name)
+			}
+			result = append(result, prefix)
+		}
+	}
+	return result, nil
+}
@@ -29,3 +29,46 @@ func TestFindDeviceReturnsPointerToSliceElement(t *testing.T) {
 		t.Fatalf("expected slice element name %q, got %v", newName, devices[0].Name)
 	}
 }
+
+func TestGetExcludedNetworks(t *testing.T) {
+	clientConfig := ClientConfig{
+		CaptivePortal: []openapi.GetClientConfig200ResponseCaptivePortal{
+			{Name: "portal", Networks: []openapi.GetClientConfig200ResponseNetworks{{Address: "172.16.1.1"}}},
+		},
+		Denylist: []openapi.GetClientConfig200ResponseDenylist{
+			{Name: "empty"},
+			{Name: "local", Networks: &openapi.GetClientConfig200ResponseNetworks1{
+				V4: []openapi.GetClientConfig200ResponseNetworks1V4{{Address: "10.1.2.3", Netmask: "255.0.0.0"}},
+				V6: []openapi.GetClientConfig200ResponseNetworks1V6{{Address: "fe80::1", Prefix: 10}},
+			}},
+		},
+	}
+	result, err := GetExcludedNetworks(&clientConfig)
+	if err != nil {
+		t.Fatalf("GetExcludedNetworks error: %v", err)
+	}
+	expected := []string{"10.0.0.0/8", "fe80::/10", "172.16.1.1/32"}
+	if len(result) != len(expected) {
+		t.Fatalf("expected %v, got %v", expected, result)
+	}
+	for i := range expected {
+		if result[i].String() != expected[i] {
+			t.Fatalf("expected %v, got %v", expected, result)
+		}
+	}
+}
+
+func TestGetExcludedNetworksInvalidNetmask(t *testing.T) {
+	for _, netmask := range []string{"255.0.255.0", "0.255.255.255", "ffff::", "invalid"} {
+		clientConfig := ClientConfig{
+			Denylist: []openapi.GetClientConfig200ResponseDenylist{
+				{Name: "local", Networks: &openapi.GetClientConfig200ResponseNetworks1{
+					V4: []openapi.GetClientConfig200ResponseNetworks1V4{{Address: "10.1.2.3", Netmask: netmask}},
+				}},
+			},
+		}
+		if result, err := GetExcludedNetworks(&clientConfig); err == nil {
+			t.Errorf("netmask %s: expected error, got %v", netmask, result)
+		}
+	}
+}
@@ -0,0 +1,81 @@
+package account
+
+import (
+	"log"
+
+	. "github.com/ViRb3/wgcf/v2/cmd/shared"
+	"github.com/ViRb3/wgcf/v2/config"
+	"github.com/ViRb3/wgcf/v2/wireguard"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var shortMsg = "Manages the named Cloudflare Warp accounts stored in the configuration file"
+
+var Cmd = &cobra.Command{
+	Use:   "account",
+	Short: shortMsg,
+	Long: FormatMessage(shortMsg, `
+Named accounts are stored as [accounts.<name>] tables. All other commands use the account selected by --account,
+or the default account if none is selected.`),
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := cmd.Help(); err != nil {
+			Fatal(err)
+		}
+	},
+}
+
+func init() {
+	Cmd.AddCommand(listCmd)
+	Cmd.AddCommand(useCmd)
+	Cmd.AddCommand(addCmd)
+	Cmd.AddCommand(removeCmd)
+	Cmd.AddCommand(showCmd)
+}
+
+type accountData struct {
+	Name       string `json:"name"`
+	DeviceId   string `json:"device_id"`
+	PublicKey  string `json:"public_key"`
+	LicenseKey string `json:"license_key"`
+	Default    bool   `json:"default"`
+	Active     bool   `json:"active"`
+}
+
+func newAccountData(name string) *accountData {
+	publicKey := ""
+	if privateKey, err := wireguard.NewKey(GetNamedAccountValue(name, config.PrivateKey)); err == nil && !privateKey.IsZero() {
+		publicKey = privateKey.Public().String()
+	}
+	return &accountData{
+		Name:       name,
+		DeviceId:   GetNamedAccountValue(name, config.DeviceId),
+		PublicKey:  publicKey,
+		LicenseKey: GetNamedAccountValue(name, config.LicenseKey),
+		Default:    viper.GetString(config.DefaultAccount) == name,
+		Active:     GetActiveAccountName() == name,
+	}
+}
+
+func (d *accountData) PrintTable() {
+	log.Println("=======================================")
+	d.printRows()
+	log.Println("=======================================")
+}
+
+func (d *accountData) printRows() {
+	log.Printf("%-13s : %s\n", "Account name", d.Name)
+	log.Printf("%-13s : %s\n", "Device id", d.DeviceId)
+	log.Printf("%-13s : %s\n", "Public key", d.PublicKey)
+	log.Printf("%-13s : %s\n", "License key", d.LicenseKey)
+	log.Printf("%-13s : %t\n", "Default", d.Default)
+	log.Printf("%-13s : %t\n", "Active", d.Active)
+}
+
+func ensureValidAccountName(name string) error {
+	if !IsValidAccountName(name) {
+		return errors.New("invalid account name, only lowercase letters, digits, '-' and '_' are allowed: " + name)
+	}
+	return nil
+}
@@ -0,0 +1,188 @@
+package account
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/ViRb3/wgcf/v2/cloudflare/cftest"
+	"github.com/ViRb3/wgcf/v2/cmd/shared"
+	"github.com/ViRb3/wgcf/v2/cmd/shared/sharedtest"
+	"github.com/ViRb3/wgcf/v2/config"
+	"github.com/ViRb3/wgcf/v2/wireguard"
+	"github.com/spf13/viper"
+)
+
+// Registers a device with the server, and sets it as the credentials of the add flags.
+func registerDevice(t *testing.T) string {
+	t.Helper()
+	key, err := wireguard.NewPrivateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	device, err := shared.CreateClient(nil).Register(context.Background(), key.Public(), "PC")
+	if err != nil {
+		t.Fatal(err)
+	}
+	deviceId = device.Id
+	accessToken = device.Token
+	privateKey = key.String()
+	licenseKey = device.Account.License
+	migrate = false
+	return device.Id
+}
+
+// Reads the config as written to disk, without any values set in memory.
+func readConfigFile(t *testing.T, configFile string) *viper.Viper {
+	t.Helper()
+	v := viper.New()
+	v.SetConfigFile(configFile)
+	if err := v.ReadInConfig(); err != nil {
+		t.Fatal(err)
+	}
+	return v
+}
+
+// The credentials of the active account must be accepted by the server.
+func checkActiveAccount(t *testing.T, expectedDeviceId string) {
+	t.Helper()
+	device, err := shared.CreateClient(shared.CreateContext()).GetSourceDevice(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if device.Id != expectedDeviceId {
+		t.Fatalf("expected the active account to be %s, got %s", expectedDeviceId, device.Id)
+	}
+}
+
+func TestAddUseRemoveAccount(t *testing.T) {
+	server := cftest.NewServer()
+	defer server.Close()
+	configFile := sharedtest.Setup(t, server)
+
+	workId := registerDevice(t)
+	if err := addAccount("work"); err != nil {
+		t.Fatal(err)
+	}
+	homeId := registerDevice(t)
+	if err := addAccount("home"); err != nil {
+		t.Fatal(err)
+	}
+	if err := addAccount("home"); err == nil {
+		t.Fatal("expected adding an existing account to fail")
+	}
+	file := readConfigFile(t, configFile)
+	if file.GetString(config.DefaultAccount) != "work" {
+		t.Fatal("the first account must become the default")
+	}
+	if file.GetString("accounts.home.device_id") != homeId || file.GetString("accounts.work.device_id") != workId {
+		t.Fatal("the accounts were not written")
+	}
+	checkActiveAccount(t, workId)
+
+	if err := useAccount("home"); err != nil {
+		t.Fatal(err)
+	}
+	if readConfigFile(t, configFile).GetString(config.DefaultAccount) != "home" {
+		t.Fatal("the default account was not written")
+	}
+	checkActiveAccount(t, homeId)
+
+	if err := removeAccount("work"); err != nil {
+		t.Fatal(err)
+	}
+	file = readConfigFile(t, configFile)
+	if file.IsSet("accounts.work") {
+		t.Fatal("the account was not removed")
+	}
+	if file.GetString("accounts.home.device_id") != homeId || file.GetString(config.DefaultAccount) != "home" {
+		t.Fatal("the other account was changed")
+	}
+	if shared.AccountExists("work") {
+		t.Fatal("the account was not removed from the loaded config")
+	}
+	checkActiveAccount(t, homeId)
+	if server.Device(workId) == nil {
+		t.Fatal("removing an account must not delete the device")
+	}
+}
+
+func TestMigrateLegacyConfig(t *testing.T) {
+	server := cftest.NewServer()
+	defer server.Close()
+	configFile := sharedtest.Setup(t, server)
+	device := sharedtest.Register(t)
+	viper.Set(config.AddressV4, device.Config.Interface.Addresses.V4)
+	viper.Set(config.ProfileMTU, 1420)
+	if err := viper.WriteConfig(); err != nil {
+		t.Fatal(err)
+	}
+	viper.Reset()
+	viper.SetConfigFile(configFile)
+	if err := shared.ReadConfig(); err != nil {
+		t.Fatal(err)
+	}
+
+	migrate = true
+	defer func() { migrate = false }()
+	if err := addAccount("main"); err != nil {
+		t.Fatal(err)
+	}
+
+	file := readConfigFile(t, configFile)
+	for _, key := range []string{config.DeviceId, config.AccessToken, config.PrivateKey, config.LicenseKey, config.AddressV4} {
+		if file.IsSet(key) {
+			t.Fatalf("%s was left in the top-level keys", key)
+		}
+	}
+	if file.GetString("accounts.main.device_id") != device.Id || file.GetString("accounts.main.address_v4") != device.Config.Interface.Addresses.V4 {
+		t.Fatal("the account was not moved")
+	}
+	if file.GetString(config.DefaultAccount) != "main" {
+		t.Fatal("the migrated account must become the default")
+	}
+	if file.GetInt(config.ProfileMTU) != 1420 {
+		t.Fatal("unrelated settings must be kept")
+	}
+	checkActiveAccount(t, device.Id)
+}
+
+func TestRemoveActiveAccount(t *testing.T) {
+	server := cftest.NewServer()
+	defer server.Close()
+	configFile := sharedtest.Setup(t, server)
+
+	workId := registerDevice(t)
+	if err := addAccount("work"); err != nil {
+		t.Fatal(err)
+	}
+	registerDevice(t)
+	if err := addAccount("home"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := removeAccount("work"); err != nil {
+		t.Fatal(err)
+	}
+	file := readConfigFile(t, configFile)
+	if file.IsSet("accounts.work") || file.IsSet(config.DefaultAccount) {
+		t.Fatal("the account and the default pointing to it must be removed")
+	}
+	if !file.IsSet("accounts.home") {
+		t.Fatal("the other account was removed")
+	}
+	if shared.GetActiveAccountName() != "" || shared.IsConfigValidAccount() {
+		t.Fatal("the removed account must no longer be active")
+	}
+	if server.Device(workId) == nil {
+		t.Fatal("removing an account must not delete the device")
+	}
+
+	info, err := os.Stat(configFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Fatalf("unexpected config file mode %v", info.Mode())
+	}
+}
@@ -0,0 +1,91 @@
+package account
+
+import (
+	"log"
+
+	. "github.com/ViRb3/wgcf/v2/cmd/shared"
+	"github.com/ViRb3/wgcf/v2/config"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var deviceId string
+var accessToken string
+var privateKey string
+var licenseKey string
+var migrate bool
+var addShortMsg = "Adds an existing account under a new name"
+
+var addCmd = &cobra.Command{
+	Use:   "add <name>",
+	Short: addShortMsg,
+	Long: FormatMessage(addShortMsg, `
+The account credentials are either given as flags, or moved from the unnamed top-level account with --migrate.
+To create a brand new account instead, use 'register --account <name>'.`),
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := addAccount(args[0]); err != nil {
+			Fatal(err)
+		}
+	},
+}
+
+func init() {
+	addCmd.PersistentFlags().StringVar(&deviceId, "device-id", "", "Device id of the account")
+	addCmd.PersistentFlags().StringVar(&accessToken, "access-token", "", "Access token of the account")
+	addCmd.PersistentFlags().StringVar(&privateKey, "private-key", "", "Base64 private key of the account")
+	addCmd.PersistentFlags().StringVar(&licenseKey, "license-key", "", "License key of the account")
+	addCmd.PersistentFlags().BoolVar(&migrate, "migrate", false, "Move the unnamed top-level account under the new name")
+}
+
+func addAccount(name string) error {
+	if err := ensureValidAccountName(name); err != nil {
+		return err
+	}
+	if AccountExists(name) {
+		return errors.New("account already exists: " + name)
+	}
+
+	if migrate {
+		if !AccountExists("") {
+			return errors.New("no top-level account detected")
+		}
+		deviceId = GetNamedAccountValue("", config.DeviceId)
+		accessToken = GetNamedAccountValue("", config.AccessToken)
+		privateKey = GetNamedAccountValue("", config.PrivateKey)
+		licenseKey = GetNamedAccountValue("", config.LicenseKey)
+	}
+	if deviceId == "" || accessToken == "" || privateKey == "" {
+		return errors.New("device id, access token and private key are required")
+	}
+
+	SetNamedAccountValue(name, config.DeviceId, deviceId)
+	SetNamedAccountValue(name, config.AccessToken, accessToken)
+	SetNamedAccountValue(name, config.PrivateKey, privateKey)
+	SetNamedAccountValue(name, config.LicenseKey, licenseKey)
+	if migrate {
+		for _, key := range config.CacheKeys {
+			if value := GetNamedAccountValue("", key); value != "" {
+				SetNamedAccountValue(name, key, value)
+			}
+		}
+	}
+	if viper.GetString(config.DefaultAccount) == "" {
+		SetConfigValue(config.DefaultAccount, name)
+	}
+	if err := WriteConfigAtomic(); err != nil {
+		return err
+	}
+	if migrate {
+		if err := RemoveAccount(""); err != nil {
+			return err
+		}
+	}
+
+	if err := PrintOutput(newAccountData(name)); err != nil {
+		return err
+	}
+	log.Println("Successfully added account:", name)
+	return nil
+}
@@ -0,0 +1,43 @@
+package account
+
+import (
+	"log"
+
+	. "github.com/ViRb3/wgcf/v2/cmd/shared"
+	"github.com/spf13/cobra"
+)
+
+var listShortMsg = "Lists all named accounts"
+
+var listCmd = &cobra.Command{
+	Use:   "list",
+	Short: listShortMsg,
+	Long:  FormatMessage(listShortMsg, ``),
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := listAccounts(); err != nil {
+			Fatal(err)
+		}
+	},
+}
+
+func listAccounts() error {
+	result := accountListData{}
+	for _, name := range GetAccountNames() {
+		result = append(result, newAccountData(name))
+	}
+	if AccountExists("") {
+		log.Println("An unnamed account is also stored in the top-level keys, use 'account add --migrate' to name it")
+	}
+	return PrintOutput(result)
+}
+
+type accountListData []*accountData
+
+func (l accountListData) PrintTable() {
+	log.Println("=======================================")
+	for _, account := range l {
+		account.printRows()
+		log.Println("=======================================")
+	}
+}
@@ -0,0 +1,38 @@
+package account
+
+import (
+	"log"
+
+	. "github.com/ViRb3/wgcf/v2/cmd/shared"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+var removeShortMsg = "Removes a named account from the configuration file"
+
+var removeCmd = &cobra.Command{
+	Use:   "remove <name>",
+	Short: removeShortMsg,
+	Long: FormatMessage(removeShortMsg, `
+The device is not unregistered from Cloudflare, only its credentials are forgotten.`),
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := removeAccount(args[0]); err != nil {
+			Fatal(err)
+		}
+	},
+}
+
+func removeAccount(name string) error {
+	if err := ensureValidAccountName(name); err != nil {
+		return err
+	}
+	if !AccountExists(name) {
+		return errors.New("account not found: " + name)
+	}
+	if err := RemoveAccount(name); err != nil {
+		return err
+	}
+	log.Println("Successfully removed account:", name)
+	return nil
+}
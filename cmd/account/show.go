@@ -0,0 +1,38 @@
+package account
+
+import (
+	. "github.com/ViRb3/wgcf/v2/cmd/shared"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+var showShortMsg = "Prints the stored details of an account"
+
+var showCmd = &cobra.Command{
+	Use:   "show [name]",
+	Short: showShortMsg,
+	Long: FormatMessage(showShortMsg, `
+Defaults to the active account. Secrets such as the access token and private key are not printed.`),
+	Args: cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		name := GetActiveAccountName()
+		if len(args) > 0 {
+			name = args[0]
+		}
+		if err := showAccount(name); err != nil {
+			Fatal(err)
+		}
+	},
+}
+
+func showAccount(name string) error {
+	if name != "" {
+		if err := ensureValidAccountName(name); err != nil {
+			return err
+		}
+	}
+	if !AccountExists(name) {
+		return errors.New("account not found: " + name)
+	}
+	return PrintOutput(newAccountData(name))
+}
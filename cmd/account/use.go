@@ -0,0 +1,39 @@
+package account
+
+import (
+	"log"
+
+	. "github.com/ViRb3/wgcf/v2/cmd/shared"
+	"github.com/ViRb3/wgcf/v2/config"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+var useShortMsg = "Sets the default account"
+
+var useCmd = &cobra.Command{
+	Use:   "use <name>",
+	Short: useShortMsg,
+	Long:  FormatMessage(useShortMsg, ``),
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := useAccount(args[0]); err != nil {
+			Fatal(err)
+		}
+	},
+}
+
+func useAccount(name string) error {
+	if err := ensureValidAccountName(name); err != nil {
+		return err
+	}
+	if !AccountExists(name) {
+		return errors.New("account not found: " + name)
+	}
+	SetConfigValue(config.DefaultAccount, name)
+	if err := WriteConfigAtomic(); err != nil {
+		return err
+	}
+	log.Println("Default account set to:", name)
+	return nil
+}
@@ -0,0 +1,68 @@
+package clientconfig
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/ViRb3/wgcf/v2/cloudflare"
+	. "github.com/ViRb3/wgcf/v2/cmd/shared"
+	"github.com/spf13/cobra"
+)
+
+var shortMsg = "Prints the client configuration published by Cloudflare Warp"
+
+var Cmd = &cobra.Command{
+	Use:   "client-config",
+	Short: shortMsg,
+	Long: FormatMessage(shortMsg, `
+Includes the captive portal hosts and denylisted networks which clients are expected to exclude from the tunnel,
+as well as the data rewarded for Warp+ and referrals.`),
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := clientConfig(cmd.Context()); err != nil {
+			Fatal(err)
+		}
+	},
+}
+
+func clientConfig(ctx context.Context) error {
+	clientConfig, err := CreateClient(nil).GetClientConfig(ctx)
+	if err != nil {
+		return err
+	}
+	return PrintOutput((*clientConfigData)(clientConfig))
+}
+
+// the schema is the client_config response as returned by Cloudflare
+type clientConfigData cloudflare.ClientConfig
+
+func (clientConfig *clientConfigData) PrintTable() {
+	log.Println("=======================================")
+	log.Printf("%-13s : %s\n", "Premium data", F32ToHumanReadable(clientConfig.PremiumDataBytes))
+	log.Printf("%-13s : %s\n", "Referral data", F32ToHumanReadable(clientConfig.ReferralRewardBytes))
+	log.Println("=======================================")
+	log.Println("Captive portals:")
+	for _, portal := range clientConfig.CaptivePortal {
+		var addresses []string
+		for _, network := range portal.Networks {
+			addresses = append(addresses, network.Address)
+		}
+		log.Printf("  %s: %s\n", portal.Name, strings.Join(addresses, ", "))
+	}
+	log.Println("=======================================")
+	log.Println("Denylist:")
+	for _, entry := range clientConfig.Denylist {
+		var addresses []string
+		if entry.Networks != nil {
+			for _, network := range entry.Networks.V4 {
+				addresses = append(addresses, network.Address+"/"+network.Netmask)
+			}
+			for _, network := range entry.Networks.V6 {
+				addresses = append(addresses, fmt.Sprintf("%s/%d", network.Address, int(network.Prefix)))
+			}
+		}
+		log.Printf("  %s (visible: %t): %s\n", entry.Name, entry.Visible, strings.Join(addresses, ", "))
+	}
+	log.Println("=======================================")
+}
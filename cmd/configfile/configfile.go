@@ -0,0 +1,90 @@
+package configfile
+
+import (
+	"log"
+	"os"
+
+	. "github.com/ViRb3/wgcf/v2/cmd/shared"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var shortMsg = "Manages the encryption of the configuration file"
+
+var Cmd = &cobra.Command{
+	Use:   "config",
+	Short: shortMsg,
+	Long: FormatMessage(shortMsg, `
+An encrypted configuration file is transparently decrypted by all commands, using the passphrase from --passphrase-file,
+the `+PassphraseEnv+` environment variable, or an interactive prompt, in that order.`),
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := cmd.Help(); err != nil {
+			Fatal(err)
+		}
+	},
+}
+
+var encryptShortMsg = "Encrypts the configuration file with a passphrase"
+
+var encryptCmd = &cobra.Command{
+	Use:   "encrypt",
+	Short: encryptShortMsg,
+	Long:  FormatMessage(encryptShortMsg, ``),
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := encryptConfig(); err != nil {
+			Fatal(err)
+		}
+	},
+}
+
+var decryptShortMsg = "Decrypts the configuration file, storing it in cleartext"
+
+var decryptCmd = &cobra.Command{
+	Use:   "decrypt",
+	Short: decryptShortMsg,
+	Long:  FormatMessage(decryptShortMsg, ``),
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := decryptConfig(); err != nil {
+			Fatal(err)
+		}
+	},
+}
+
+func init() {
+	Cmd.AddCommand(encryptCmd)
+	Cmd.AddCommand(decryptCmd)
+}
+
+func encryptConfig() error {
+	if ConfigPassphrase != "" {
+		return errors.New("configuration file is already encrypted")
+	}
+	if _, err := os.Stat(viper.ConfigFileUsed()); err != nil {
+		return errors.WithMessage(err, "no configuration detected")
+	}
+	passphrase, err := GetPassphrase(true)
+	if err != nil {
+		return err
+	}
+	ConfigPassphrase = passphrase
+	if err := WriteConfigAtomic(); err != nil {
+		return err
+	}
+	log.Println("Successfully encrypted configuration file:", viper.ConfigFileUsed())
+	return nil
+}
+
+func decryptConfig() error {
+	if ConfigPassphrase == "" {
+		return errors.New("configuration file is not encrypted")
+	}
+	ConfigPassphrase = ""
+	if err := WriteConfigAtomic(); err != nil {
+		return err
+	}
+	log.Println("Successfully decrypted configuration file:", viper.ConfigFileUsed())
+	return nil
+}
@@ -0,0 +1,54 @@
+package connect
+
+import (
+	"context"
+	"log"
+	"time"
+
+	. "github.com/ViRb3/wgcf/v2/cmd/shared"
+	"github.com/ViRb3/wgcf/v2/wireguard"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+var interfaceName string
+var offline bool
+var handshakeTimeout time.Duration
+var shortMsg = "Connects to Cloudflare Warp with an embedded WireGuard tunnel"
+
+var Cmd = &cobra.Command{
+	Use:   "connect",
+	Short: shortMsg,
+	Long: FormatMessage(shortMsg, `
+Brings up the WireGuard tunnel of the current account inside wgcf, without wg-quick, until interrupted.
+By default, a TUN interface is created and its addresses and routes are configured like wg-quick,
+which requires root or CAP_NET_ADMIN on Linux. The DNS servers are set with resolvconf, if it's installed.
+If TUN is not available, connect fails; to use Warp without privileges, run the proxy command instead.
+The profile settings of generate apply, with a persistent keepalive of 25 seconds by default.`),
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := connect(cmd.Context(), cmd.Flags()); err != nil {
+			Fatal(err)
+		}
+	},
+}
+
+func init() {
+	Cmd.PersistentFlags().StringVarP(&interfaceName, "interface", "i", wireguard.InterfaceName, "Name of the TUN interface")
+	Cmd.PersistentFlags().BoolVar(&offline, "offline", false, "Use the cached device configuration, without contacting the API")
+	Cmd.PersistentFlags().DurationVar(&handshakeTimeout, "handshake-timeout", 10*time.Second, "How long to wait for the first handshake before warning")
+	AddProfileFlags(Cmd.PersistentFlags())
+}
+
+func connect(ctx context.Context, flags *pflag.FlagSet) error {
+	if interfaceName == "" {
+		return errors.New("the interface name must not be empty")
+	}
+	tunnel, err := OpenTunnel(ctx, flags, offline, interfaceName)
+	if err != nil {
+		return err
+	}
+	defer tunnel.Close()
+	log.Println("Tunnel is up on", tunnel.Name())
+	return MonitorHandshake(ctx, tunnel, handshakeTimeout)
+}
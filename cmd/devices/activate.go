@@ -0,0 +1,65 @@
+package devices
+
+import (
+	"context"
+	"log"
+
+	. "github.com/ViRb3/wgcf/v2/cmd/shared"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+var activateShortMsg = "Activates a device bound to the current Cloudflare Warp account"
+
+var activateCmd = &cobra.Command{
+	Use:   "activate <id>",
+	Short: activateShortMsg,
+	Long:  FormatMessage(activateShortMsg, ``),
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := setDeviceActive(cmd.Context(), args[0], true); err != nil {
+			Fatal(err)
+		}
+	},
+}
+
+var deactivateShortMsg = "Deactivates a device bound to the current Cloudflare Warp account"
+
+var deactivateCmd = &cobra.Command{
+	Use:   "deactivate <id>",
+	Short: deactivateShortMsg,
+	Long: FormatMessage(deactivateShortMsg, `
+The device stays bound to the account, but no longer counts towards the device limit.`),
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := setDeviceActive(cmd.Context(), args[0], false); err != nil {
+			Fatal(err)
+		}
+	},
+}
+
+func setDeviceActive(ctx context.Context, deviceId string, active bool) error {
+	if err := ensureAccount(); err != nil {
+		return err
+	}
+
+	cfg := CreateContext()
+	client := CreateClient(cfg)
+	device, err := client.UpdateBoundDeviceActive(ctx, deviceId, active)
+	if err != nil {
+		return err
+	}
+	if device.Active != active {
+		return errors.New("failed to update device active state")
+	}
+
+	if err := PrintOutput(newBoundDeviceData(device, cfg.DeviceId)); err != nil {
+		return err
+	}
+	if active {
+		log.Println("Successfully activated device")
+	} else {
+		log.Println("Successfully deactivated device")
+	}
+	return nil
+}
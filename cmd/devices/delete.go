@@ -0,0 +1,43 @@
+package devices
+
+import (
+	"context"
+	"log"
+
+	. "github.com/ViRb3/wgcf/v2/cmd/shared"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+var deleteShortMsg = "Deletes a device bound to the current Cloudflare Warp account"
+
+var deleteCmd = &cobra.Command{
+	Use:   "delete <id>",
+	Short: deleteShortMsg,
+	Long: FormatMessage(deleteShortMsg, `
+The device is unbound from the account and its credentials stop working.
+The current device can't be deleted, as that would leave this configuration without a working account.`),
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := deleteDevice(cmd.Context(), args[0]); err != nil {
+			Fatal(err)
+		}
+	},
+}
+
+func deleteDevice(ctx context.Context, deviceId string) error {
+	if err := ensureAccount(); err != nil {
+		return err
+	}
+
+	cfg := CreateContext()
+	if deviceId == cfg.DeviceId {
+		return errors.New("refusing to delete the current device, register a new account instead")
+	}
+	client := CreateClient(cfg)
+	if err := client.DeleteBoundDevice(ctx, deviceId); err != nil {
+		return err
+	}
+	log.Println("Successfully deleted device")
+	return nil
+}
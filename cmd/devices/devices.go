@@ -0,0 +1,88 @@
+package devices
+
+import (
+	"log"
+
+	"github.com/ViRb3/wgcf/v2/cloudflare"
+	. "github.com/ViRb3/wgcf/v2/cmd/shared"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+var shortMsg = "Manages all devices bound to the current Cloudflare Warp account"
+
+var Cmd = &cobra.Command{
+	Use:   "devices",
+	Short: shortMsg,
+	Long: FormatMessage(shortMsg, `
+Please note that there is a maximum limit of 5 active devices linked to the same account at a given time.
+Deactivating a device frees up its slot.`),
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := cmd.Help(); err != nil {
+			Fatal(err)
+		}
+	},
+}
+
+func init() {
+	Cmd.AddCommand(listCmd)
+	Cmd.AddCommand(renameCmd)
+	Cmd.AddCommand(activateCmd)
+	Cmd.AddCommand(deactivateCmd)
+	Cmd.AddCommand(deleteCmd)
+}
+
+type boundDeviceData struct {
+	Id         string `json:"id"`
+	Name       string `json:"name"`
+	Model      string `json:"model"`
+	Type       string `json:"type"`
+	Role       string `json:"role"`
+	Active     bool   `json:"active"`
+	Created    string `json:"created"`
+	Activated  string `json:"activated"`
+	ThisDevice bool   `json:"this_device"`
+}
+
+func newBoundDeviceData(device *cloudflare.BoundDevice, thisDeviceId string) *boundDeviceData {
+	name := ""
+	if device.Name != nil {
+		name = *device.Name
+	}
+	return &boundDeviceData{
+		Id:         device.Id,
+		Name:       name,
+		Model:      device.Model,
+		Type:       device.Type,
+		Role:       device.Role,
+		Active:     device.Active,
+		Created:    device.Created,
+		Activated:  device.Activated,
+		ThisDevice: device.Id == thisDeviceId,
+	}
+}
+
+func (d *boundDeviceData) PrintTable() {
+	log.Println("=======================================")
+	d.printRows()
+	log.Println("=======================================")
+}
+
+func (d *boundDeviceData) printRows() {
+	log.Printf("%-13s : %s\n", "Device id", d.Id)
+	log.Printf("%-13s : %s\n", "Device name", d.Name)
+	log.Printf("%-13s : %s\n", "Device model", d.Model)
+	log.Printf("%-13s : %s\n", "Device type", d.Type)
+	log.Printf("%-13s : %s\n", "Role", d.Role)
+	log.Printf("%-13s : %t\n", "Device active", d.Active)
+	log.Printf("%-13s : %s\n", "Created", d.Created)
+	log.Printf("%-13s : %s\n", "Activated", d.Activated)
+	log.Printf("%-13s : %t\n", "This device", d.ThisDevice)
+}
+
+func ensureAccount() error {
+	if !IsConfigValidAccount() {
+		return errors.New("no account detected")
+	}
+	return nil
+}
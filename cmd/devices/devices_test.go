@@ -0,0 +1,145 @@
+package devices
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"testing"
+
+	"github.com/ViRb3/wgcf/v2/cloudflare/cftest"
+	"github.com/ViRb3/wgcf/v2/cmd/shared"
+	"github.com/ViRb3/wgcf/v2/cmd/shared/sharedtest"
+	"github.com/ViRb3/wgcf/v2/config"
+	"github.com/spf13/viper"
+)
+
+// Registers a device and binds it to a Warp+ account with two more devices,
+// returns the id of the registered device and of the other two.
+func setupAccount(t *testing.T, server *cftest.Server) (string, []string) {
+	sharedtest.Setup(t, server)
+	device := sharedtest.Register(t)
+	viper.Set(config.LicenseKey, server.CreatePlusAccount(2))
+	client := shared.CreateClient(shared.CreateContext())
+	if _, err := client.UpdateLicenseKey(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	devices, err := client.GetBoundDevices(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var otherIds []string
+	for _, boundDevice := range devices {
+		if boundDevice.Id != device.Id {
+			otherIds = append(otherIds, boundDevice.Id)
+		}
+	}
+	if len(otherIds) != 2 {
+		t.Fatalf("expected 2 other devices, got %d", len(otherIds))
+	}
+	return device.Id, otherIds
+}
+
+func listDevicesJson(t *testing.T) []boundDeviceData {
+	var stdout bytes.Buffer
+	shared.Stdout = &stdout
+	shared.OutputFormat = shared.OutputJson
+	defer func() {
+		shared.Stdout = os.Stdout
+		shared.OutputFormat = shared.OutputTable
+	}()
+	if err := listDevices(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	var devices []boundDeviceData
+	if err := json.Unmarshal(stdout.Bytes(), &devices); err != nil {
+		t.Fatal(err)
+	}
+	return devices
+}
+
+func TestListDevices(t *testing.T) {
+	server := cftest.NewServer()
+	defer server.Close()
+	thisId, otherIds := setupAccount(t, server)
+
+	devices := listDevicesJson(t)
+	if len(devices) != 3 {
+		t.Fatalf("expected 3 devices, got %d", len(devices))
+	}
+	for _, device := range devices {
+		if device.ThisDevice != (device.Id == thisId) {
+			t.Fatalf("device %s has this_device %t", device.Id, device.ThisDevice)
+		}
+		if device.Id == otherIds[0] && (device.Model != "Phone" || device.Type != "Android" || !device.Active) {
+			t.Fatalf("unexpected device %+v", device)
+		}
+	}
+}
+
+func TestRenameDevice(t *testing.T) {
+	server := cftest.NewServer()
+	defer server.Close()
+	_, otherIds := setupAccount(t, server)
+
+	if err := renameDevice(context.Background(), otherIds[0], "old-phone"); err != nil {
+		t.Fatal(err)
+	}
+	if name := server.Device(otherIds[0]).Name; name == nil || *name != "old-phone" {
+		t.Fatal("the device was not renamed")
+	}
+	if server.Device(otherIds[1]).Name != nil {
+		t.Fatal("another device was renamed")
+	}
+}
+
+func TestDeactivateAndActivateDevice(t *testing.T) {
+	server := cftest.NewServer()
+	defer server.Close()
+	_, otherIds := setupAccount(t, server)
+
+	if err := setDeviceActive(context.Background(), otherIds[0], false); err != nil {
+		t.Fatal(err)
+	}
+	if server.Device(otherIds[0]).Active {
+		t.Fatal("the device was not deactivated")
+	}
+	if err := setDeviceActive(context.Background(), otherIds[0], true); err != nil {
+		t.Fatal(err)
+	}
+	if !server.Device(otherIds[0]).Active {
+		t.Fatal("the device was not activated")
+	}
+}
+
+func TestDeleteDevice(t *testing.T) {
+	server := cftest.NewServer()
+	defer server.Close()
+	_, otherIds := setupAccount(t, server)
+
+	if err := deleteDevice(context.Background(), otherIds[0]); err != nil {
+		t.Fatal(err)
+	}
+	if server.Device(otherIds[0]) != nil {
+		t.Fatal("the device was not deleted")
+	}
+	if len(listDevicesJson(t)) != 2 {
+		t.Fatal("the deleted device is still listed")
+	}
+}
+
+func TestDeleteCurrentDeviceIsRejected(t *testing.T) {
+	server := cftest.NewServer()
+	defer server.Close()
+	thisId, _ := setupAccount(t, server)
+
+	if err := deleteDevice(context.Background(), thisId); err == nil {
+		t.Fatal("expected deleting the current device to fail")
+	}
+	if server.Requests(cftest.RouteDeleteBoundDevice) != 0 {
+		t.Fatal("the delete request was sent")
+	}
+	if server.Device(thisId) == nil {
+		t.Fatal("the current device was deleted")
+	}
+}
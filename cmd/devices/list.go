@@ -0,0 +1,52 @@
+package devices
+
+import (
+	"context"
+	"log"
+
+	. "github.com/ViRb3/wgcf/v2/cmd/shared"
+	"github.com/spf13/cobra"
+)
+
+var listShortMsg = "Lists all devices bound to the current Cloudflare Warp account"
+
+var listCmd = &cobra.Command{
+	Use:   "list",
+	Short: listShortMsg,
+	Long:  FormatMessage(listShortMsg, ``),
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := listDevices(cmd.Context()); err != nil {
+			Fatal(err)
+		}
+	},
+}
+
+func listDevices(ctx context.Context) error {
+	if err := ensureAccount(); err != nil {
+		return err
+	}
+
+	cfg := CreateContext()
+	client := CreateClient(cfg)
+	devices, err := client.GetBoundDevices(ctx)
+	if err != nil {
+		return err
+	}
+
+	result := boundDeviceListData{}
+	for i := range devices {
+		result = append(result, newBoundDeviceData(&devices[i], cfg.DeviceId))
+	}
+	return PrintOutput(result)
+}
+
+type boundDeviceListData []*boundDeviceData
+
+func (l boundDeviceListData) PrintTable() {
+	log.Println("=======================================")
+	for _, device := range l {
+		device.printRows()
+		log.Println("=======================================")
+	}
+}
@@ -0,0 +1,46 @@
+package devices
+
+import (
+	"context"
+	"log"
+
+	. "github.com/ViRb3/wgcf/v2/cmd/shared"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+var renameShortMsg = "Renames a device bound to the current Cloudflare Warp account"
+
+var renameCmd = &cobra.Command{
+	Use:   "rename <id> <name>",
+	Short: renameShortMsg,
+	Long:  FormatMessage(renameShortMsg, ``),
+	Args:  cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := renameDevice(cmd.Context(), args[0], args[1]); err != nil {
+			Fatal(err)
+		}
+	},
+}
+
+func renameDevice(ctx context.Context, deviceId string, name string) error {
+	if err := ensureAccount(); err != nil {
+		return err
+	}
+
+	cfg := CreateContext()
+	client := CreateClient(cfg)
+	device, err := client.UpdateBoundDeviceName(ctx, deviceId, name)
+	if err != nil {
+		return err
+	}
+	if device.Name == nil || *device.Name != name {
+		return errors.New("could not update device name")
+	}
+
+	if err := PrintOutput(newBoundDeviceData(device, cfg.DeviceId)); err != nil {
+		return err
+	}
+	log.Println("Successfully renamed device")
+	return nil
+}
@@ -0,0 +1,23 @@
+package endpoint
+
+import (
+	. "github.com/ViRb3/wgcf/v2/cmd/shared"
+	"github.com/spf13/cobra"
+)
+
+var shortMsg = "Finds the Cloudflare Warp endpoints reachable from this network"
+
+var Cmd = &cobra.Command{
+	Use:   "endpoint",
+	Short: shortMsg,
+	Long:  FormatMessage(shortMsg, ``),
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := cmd.Help(); err != nil {
+			Fatal(err)
+		}
+	},
+}
+
+func init() {
+	Cmd.AddCommand(scanCmd)
+}
@@ -0,0 +1,118 @@
+package endpoint
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	. "github.com/ViRb3/wgcf/v2/cmd/shared"
+	"github.com/ViRb3/wgcf/v2/wireguard"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+var ports []uint
+var scanOptions = wireguard.DefaultScanOptions
+var offline bool
+var scanShortMsg = "Scans the Cloudflare Warp endpoints for the fastest reachable one"
+
+var scanCmd = &cobra.Command{
+	Use:   "scan",
+	Short: scanShortMsg,
+	Long: FormatMessage(scanShortMsg, `
+Sends WireGuard handshake initiations with the keys of the current device to the IPv4 and IPv6
+endpoint addresses of the device, on every known Warp port, and measures the round-trip time and loss.
+The results are sorted from best to worst. Use generate --endpoint best to generate a profile with the best endpoint.`),
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := scanEndpoints(cmd.Context()); err != nil {
+			Fatal(err)
+		}
+	},
+}
+
+func init() {
+	scanCmd.PersistentFlags().UintSliceVar(&ports, "ports", nil, "Ports to scan (defaults to the known Warp ports)")
+	scanCmd.PersistentFlags().IntVar(&scanOptions.Count, "count", scanOptions.Count, "Handshake initiations to send to each endpoint")
+	scanCmd.PersistentFlags().DurationVar(&scanOptions.Timeout, "probe-timeout", scanOptions.Timeout, "How long to wait for each handshake response")
+	scanCmd.PersistentFlags().IntVar(&scanOptions.Concurrency, "concurrency", scanOptions.Concurrency, "Endpoints to scan at the same time")
+	scanCmd.PersistentFlags().BoolVar(&offline, "offline", false, "Use the cached device configuration, without contacting the API")
+}
+
+func scanEndpoints(ctx context.Context) error {
+	if !IsConfigValidAccount() {
+		return errors.New("no account detected")
+	}
+	if scanOptions.Count < 1 || scanOptions.Concurrency < 1 || scanOptions.Timeout <= 0 {
+		return errors.New("count, concurrency and probe timeout must be positive")
+	}
+	scanPorts := wireguard.EndpointPorts
+	if len(ports) > 0 {
+		scanPorts = nil
+		for _, port := range ports {
+			if port == 0 || port > 65535 {
+				return errors.Errorf("invalid port %d", port)
+			}
+			scanPorts = append(scanPorts, uint16(port))
+		}
+	}
+
+	thisDevice, err := GetDeviceConfig(ctx, offline)
+	if err != nil {
+		return err
+	}
+	results, err := ScanDeviceEndpoints(ctx, thisDevice, CreateContext().PrivateKey, scanPorts, scanOptions)
+	if err != nil {
+		return err
+	}
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+
+	output := endpointListData{}
+	for i := range results {
+		output = append(output, newEndpointData(&results[i]))
+	}
+	if err := PrintOutput(output); err != nil {
+		return err
+	}
+	if len(results) == 0 || results[0].Received == 0 {
+		return errors.New("no endpoint responded, UDP may be blocked on this network")
+	}
+	log.Println("Best endpoint:", results[0].Endpoint)
+	return nil
+}
+
+type endpointData struct {
+	Endpoint string `json:"endpoint"`
+	Sent     int    `json:"sent"`
+	Received int    `json:"received"`
+	// fraction of the initiations without a response, between 0 and 1
+	Loss float64 `json:"loss"`
+	// average round-trip time in milliseconds, 0 if there was no response
+	RTT float64 `json:"rtt_ms"`
+}
+
+func newEndpointData(result *wireguard.ScanResult) *endpointData {
+	return &endpointData{
+		Endpoint: result.Endpoint.String(),
+		Sent:     result.Sent,
+		Received: result.Received,
+		Loss:     result.GetLoss(),
+		RTT:      float64(result.RTT) / float64(time.Millisecond),
+	}
+}
+
+type endpointListData []*endpointData
+
+func (l endpointListData) PrintTable() {
+	log.Printf("%-42s : %10s : %s\n", "Endpoint", "RTT", "Loss")
+	for _, endpoint := range l {
+		rtt := "-"
+		if endpoint.Received > 0 {
+			rtt = fmt.Sprintf("%.1f ms", endpoint.RTT)
+		}
+		log.Printf("%-42s : %10s : %.0f%%\n", endpoint.Endpoint, rtt, endpoint.Loss*100)
+	}
+}
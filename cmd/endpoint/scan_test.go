@@ -0,0 +1,51 @@
+package endpoint
+
+import (
+	"context"
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/ViRb3/wgcf/v2/cmd/shared"
+	"github.com/ViRb3/wgcf/v2/config"
+	"github.com/ViRb3/wgcf/v2/wireguard/wgtest"
+	"github.com/spf13/viper"
+)
+
+func TestFindBestEndpointOffline(t *testing.T) {
+	peer, err := wgtest.NewPeer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer peer.Close()
+	peerEndpoint := netip.MustParseAddrPort(peer.Endpoint)
+
+	// the cached device configuration points at the peer
+	viper.Reset()
+	for key, value := range map[string]string{
+		config.DeviceId:      "device",
+		config.AccessToken:   "token",
+		config.PrivateKey:    peer.ClientPrivateKey,
+		config.PeerPublicKey: peer.PublicKey,
+		config.EndpointHost:  peer.Endpoint,
+		config.EndpointV4:    "127.0.0.1:0",
+		config.CachedAt:      time.Now().UTC().Format(time.RFC3339),
+	} {
+		viper.Set(key, value)
+	}
+	thisDevice, err := shared.GetDeviceConfig(context.Background(), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	options := scanOptions
+	options.Timeout = 200 * time.Millisecond
+	results, err := shared.ScanDeviceEndpoints(context.Background(), thisDevice, peer.ClientPrivateKey,
+		[]uint16{peerEndpoint.Port() + 1, peerEndpoint.Port()}, options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 2 || results[0].Endpoint != peerEndpoint || results[0].GetLoss() != 0 || results[1].Received != 0 {
+		t.Fatalf("unexpected results %+v", results)
+	}
+}
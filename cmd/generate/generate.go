@@ -1,66 +1,217 @@
 package generate
 
 import (
+	"context"
 	"log"
+	"net"
+	"net/netip"
+	"slices"
+	"strings"
+	"time"
 
 	"github.com/ViRb3/wgcf/v2/cloudflare"
 	. "github.com/ViRb3/wgcf/v2/cmd/shared"
-	"github.com/ViRb3/wgcf/v2/config"
-	"github.com/ViRb3/wgcf/v2/util"
 	"github.com/ViRb3/wgcf/v2/wireguard"
 	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
-	"github.com/spf13/viper"
+	"github.com/spf13/pflag"
 )
 
+// Scans for the fastest endpoint.
+const endpointBest = "best"
+
 var profileFile string
+var excludeDenylist bool
+var excludedNetworks []string
+var offline bool
+var endpoint string
+var endpointMode string
+
 var shortMsg = "Generates a WireGuard profile from the current Cloudflare Warp account"
 
 var Cmd = &cobra.Command{
 	Use:   "generate",
 	Short: shortMsg,
-	Long:  FormatMessage(shortMsg, ``),
+	Long: FormatMessage(shortMsg, `
+By default, all traffic is routed through the tunnel. Use --exclude-denylist and --exclude
+to generate a split tunnel which bypasses the given networks.
+With --offline, the profile is generated from the device configuration cached by the last
+register, update, status or generate, without contacting the API.
+With --template, the profile is rendered from a Go text/template file instead of a built-in
+format, see the README for the available fields and functions.
+With --endpoint best, the endpoints are scanned like endpoint scan does, and the fastest is used.
+With --endpoint-mode, the IPv4 or IPv6 address of the endpoint is used instead of its host name,
+for networks where the host name can't be resolved.`),
 	Run: func(cmd *cobra.Command, args []string) {
-		if err := generateProfile(); err != nil {
-			log.Fatal(util.GetErrorMessage(err))
+		if err := generateProfile(cmd.Context(), cmd.Flags()); err != nil {
+			Fatal(err)
 		}
 	},
 }
 
 func init() {
-	Cmd.PersistentFlags().StringVarP(&profileFile, "profile", "p", "wgcf-profile.conf", "WireGuard profile file")
+	Cmd.PersistentFlags().StringVarP(&profileFile, "profile", "p", "wgcf-profile.conf", "WireGuard profile file (the extension follows the format by default)")
+	AddFormatFlags(Cmd.PersistentFlags())
+	Cmd.PersistentFlags().StringVar(&endpoint, "endpoint", "", "Peer endpoint as host:port, or "+endpointBest+" to scan for the fastest reachable one (defaults to the API's)")
+	Cmd.PersistentFlags().StringVar(&endpointMode, "endpoint-mode", wireguard.EndpointModeHost, "Peer endpoint address, one of: "+strings.Join(wireguard.EndpointModes, ", ")+" (auto picks IPv4 or IPv6 by the available routes)")
+	Cmd.PersistentFlags().BoolVar(&excludeDenylist, "exclude-denylist", false, "Exclude the denylisted and captive portal networks from the client configuration")
+	Cmd.PersistentFlags().StringSliceVar(&excludedNetworks, "exclude", nil, "Networks (CIDR) to exclude from the tunnel, e.g. 10.0.0.0/8,192.168.0.0/16")
+	Cmd.PersistentFlags().BoolVar(&offline, "offline", false, "Generate the profile from the cached device configuration, without contacting the API")
+	AddProfileFlags(Cmd.PersistentFlags())
 }
 
-func generateProfile() error {
+func generateProfile(ctx context.Context, flags *pflag.FlagSet) error {
 	if !IsConfigValidAccount() {
 		return errors.New("no account detected")
 	}
 
-	ctx := CreateContext()
-	thisDevice, err := cloudflare.GetSourceDevice(ctx)
+	formatter, profilePath, err := GetFormatter(flags, profileFile)
 	if err != nil {
 		return err
 	}
-	boundDevice, err := cloudflare.GetSourceBoundDevice(ctx)
+	if endpoint != "" && flags.Changed("endpoint-mode") {
+		return errors.New("--endpoint and --endpoint-mode can't be used together")
+	}
+	if !slices.Contains(wireguard.EndpointModes, endpointMode) {
+		return errors.Errorf("invalid endpoint mode %s, must be one of: %s", endpointMode, strings.Join(wireguard.EndpointModes, ", "))
+	}
+	if offline && excludeDenylist {
+		return errors.New("--exclude-denylist requires the API and can't be used with --offline")
+	}
+	profileData, err := GetProfileSettings(flags)
 	if err != nil {
 		return err
 	}
+	if err := excludeAllowedIPs(ctx, profileData); err != nil {
+		return err
+	}
+	if offline {
+		return generateOfflineProfile(ctx, formatter, profileData, profilePath)
+	}
 
-	profile, err := wireguard.NewProfile(&wireguard.ProfileData{
-		PrivateKey: viper.GetString(config.PrivateKey),
-		Address1:   thisDevice.Config.Interface.Addresses.V4,
-		Address2:   thisDevice.Config.Interface.Addresses.V6,
-		PublicKey:  thisDevice.Config.Peers[0].PublicKey,
-		Endpoint:   thisDevice.Config.Peers[0].Endpoint.Host,
-	})
+	cfg := CreateContext()
+	client := CreateClient(cfg)
+	thisDevice, err := client.GetSourceDevice(ctx)
 	if err != nil {
 		return err
 	}
-	if err := profile.Save(profileFile); err != nil {
+	boundDevice, err := client.GetSourceBoundDevice(ctx)
+	if err != nil {
 		return err
 	}
 
-	PrintDeviceData(thisDevice, boundDevice)
-	log.Println("Successfully generated WireGuard profile:", profileFile)
+	if CacheDeviceConfig(thisDevice) {
+		if err := WriteConfigAtomic(); err != nil {
+			log.Println("Warning: failed to cache device configuration:", err)
+		}
+	}
+
+	SetProfileDevice(profileData, thisDevice, cfg.PrivateKey)
+	if err := setEndpoint(ctx, profileData, thisDevice); err != nil {
+		return err
+	}
+	paths, err := SaveProfile(profileData, formatter, profilePath)
+	if err != nil {
+		return err
+	}
+
+	deviceData := NewDeviceData(thisDevice, boundDevice)
+	deviceData.ProfilePath = strings.Join(paths, ", ")
+	if err := PrintOutput(deviceData); err != nil {
+		return err
+	}
+	log.Println("Successfully generated WireGuard profile:", strings.Join(paths, ", "))
+	return nil
+}
+
+// only the profile is generated, as the device details aren't cached
+func generateOfflineProfile(ctx context.Context, formatter wireguard.Formatter, profileData *wireguard.ProfileData, profilePath string) error {
+	thisDevice, cachedAt, err := GetCachedDevice()
+	if err != nil {
+		return err
+	}
+	if age := time.Since(cachedAt); age > DeviceCacheMaxAge {
+		log.Printf("Warning: the cached device configuration is %d days old and may be outdated, "+
+			"run status once while online to refresh it\n", int(age.Hours()/24))
+	}
+	SetProfileDevice(profileData, thisDevice, CreateContext().PrivateKey)
+	if err := setEndpoint(ctx, profileData, thisDevice); err != nil {
+		return err
+	}
+	paths, err := SaveProfile(profileData, formatter, profilePath)
+	if err != nil {
+		return err
+	}
+	log.Println("Successfully generated WireGuard profile from cached configuration:", strings.Join(paths, ", "))
+	return nil
+}
+
+// overrides the endpoint host returned by the API, if requested
+func setEndpoint(ctx context.Context, profileData *wireguard.ProfileData, thisDevice *cloudflare.Device) error {
+	switch endpoint {
+	case "":
+		modeEndpoint, err := profileData.GetEndpointForMode(endpointMode)
+		if err != nil {
+			return err
+		}
+		profileData.Endpoint = modeEndpoint
+	case endpointBest:
+		log.Println("Scanning endpoints, this may take a few seconds")
+		best, err := FindBestEndpoint(ctx, thisDevice, profileData.PrivateKey)
+		if err != nil {
+			return err
+		}
+		log.Println("Using best endpoint:", best)
+		profileData.Endpoint = best.String()
+	default:
+		if _, _, err := net.SplitHostPort(endpoint); err != nil {
+			return errors.WithMessage(err, "invalid endpoint")
+		}
+		profileData.Endpoint = endpoint
+	}
+	return nil
+}
+
+// subtracts the excluded networks from the allowed IPs of the profile
+func excludeAllowedIPs(ctx context.Context, profileData *wireguard.ProfileData) error {
+	var excluded []netip.Prefix
+	for _, network := range excludedNetworks {
+		prefix, err := wireguard.ParsePrefix(network)
+		if err != nil {
+			return errors.WithMessage(err, "exclude")
+		}
+		excluded = append(excluded, prefix)
+	}
+	if excludeDenylist {
+		clientConfig, err := CreateClient(nil).GetClientConfig(ctx)
+		if err != nil {
+			return err
+		}
+		denylist, err := cloudflare.GetExcludedNetworks(clientConfig)
+		if err != nil {
+			return err
+		}
+		excluded = append(excluded, denylist...)
+	}
+	if len(excluded) == 0 {
+		return nil
+	}
+
+	var base []netip.Prefix
+	for _, network := range profileData.GetAllowedIPs() {
+		prefix, err := wireguard.ParsePrefix(network)
+		if err != nil {
+			return err
+		}
+		base = append(base, prefix)
+	}
+	var allowedIPs []string
+	for _, prefix := range wireguard.SubtractPrefixes(base, excluded) {
+		allowedIPs = append(allowedIPs, prefix.String())
+	}
+	if len(allowedIPs) == 0 {
+		return errors.New("all networks are excluded")
+	}
+	profileData.AllowedIPs = allowedIPs
 	return nil
 }
@@ -0,0 +1,60 @@
+package cmd
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ViRb3/wgcf/v2/cloudflare/cftest"
+	"github.com/ViRb3/wgcf/v2/cmd/shared"
+	"github.com/ViRb3/wgcf/v2/config"
+)
+
+var updateGolden = flag.Bool("update", false, "update the golden files")
+
+// Runs wgcf like execute, and returns what it printed to stdout, with dir replaced by <dir>.
+func executeOutput(t *testing.T, server *cftest.Server, dir string, args ...string) string {
+	var stdout bytes.Buffer
+	shared.Stdout = &stdout
+	defer func() { shared.Stdout = os.Stdout }()
+	execute(t, server, dir, args...)
+	return strings.ReplaceAll(stdout.String(), dir, "<dir>")
+}
+
+func checkGolden(t *testing.T, name string, output string) {
+	t.Helper()
+	goldenFile := filepath.Join("testdata", name+".golden")
+	if *updateGolden {
+		if err := os.WriteFile(goldenFile, []byte(output), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	expected, err := os.ReadFile(goldenFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(expected) != output {
+		t.Fatalf("%s does not match, got:\n%s", goldenFile, output)
+	}
+}
+
+// The machine-readable schema is what scripts depend on, so any change to it must be deliberate.
+func TestOutputGolden(t *testing.T) {
+	server := cftest.NewServer()
+	defer server.Close()
+	dir := t.TempDir()
+
+	checkGolden(t, "register.json", executeOutput(t, server, dir, "register", "--accept-tos", "--name", "test-device", "--output", "json"))
+	checkGolden(t, "generate.json", executeOutput(t, server, dir, "generate", "--profile", filepath.Join(dir, "wgcf-profile.conf"), "--output", "json"))
+	checkGolden(t, "status.json", executeOutput(t, server, dir, "status", "--output", "json"))
+	checkGolden(t, "status.yaml", executeOutput(t, server, dir, "status", "--output", "yaml"))
+
+	shared.SetAccountValue(config.LicenseKey, server.CreatePlusAccount(1))
+	if err := shared.WriteConfigAtomic(); err != nil {
+		t.Fatal(err)
+	}
+	checkGolden(t, "update.json", executeOutput(t, server, dir, "update", "--name", "updated-device", "--output", "json"))
+}
@@ -0,0 +1,99 @@
+package proxy
+
+import (
+	"context"
+	"log"
+	"net"
+	"time"
+
+	. "github.com/ViRb3/wgcf/v2/cmd/shared"
+	"github.com/ViRb3/wgcf/v2/proxy"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+var socks5Address string
+var httpAddress string
+var offline bool
+var handshakeTimeout time.Duration
+var shortMsg = "Runs a SOCKS5 and/or HTTP proxy which forwards through Cloudflare Warp"
+
+var Cmd = &cobra.Command{
+	Use:   "proxy",
+	Short: shortMsg,
+	Long: FormatMessage(shortMsg, `
+Brings up the WireGuard tunnel of the current account on a userspace network stack, which requires
+no privileges, and forwards proxy connections through it until interrupted.
+The SOCKS5 proxy supports TCP and UDP (UDP ASSOCIATE), the HTTP proxy supports CONNECT and plain HTTP.
+Neither requires authentication, so only listen on trusted networks.
+Names are resolved through the tunnel with the DNS servers of the profile settings.`),
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := runProxy(cmd.Context(), cmd.Flags()); err != nil {
+			Fatal(err)
+		}
+	},
+}
+
+func init() {
+	Cmd.PersistentFlags().StringVar(&socks5Address, "socks5", "", "Address to serve the SOCKS5 proxy on, e.g. 127.0.0.1:1080")
+	Cmd.PersistentFlags().StringVar(&httpAddress, "http", "", "Address to serve the HTTP proxy on, e.g. 127.0.0.1:8080")
+	Cmd.PersistentFlags().BoolVar(&offline, "offline", false, "Use the cached device configuration, without contacting the API")
+	Cmd.PersistentFlags().DurationVar(&handshakeTimeout, "handshake-timeout", 10*time.Second, "How long to wait for the first handshake before warning")
+	AddProfileFlags(Cmd.PersistentFlags())
+}
+
+func runProxy(ctx context.Context, flags *pflag.FlagSet) error {
+	if socks5Address == "" && httpAddress == "" {
+		return errors.New("at least one of --socks5 and --http is required")
+	}
+	// listen first, so an address in use fails before the tunnel is brought up
+	var socks5Listener, httpListener net.Listener
+	var err error
+	if socks5Address != "" {
+		if socks5Listener, err = net.Listen("tcp", socks5Address); err != nil {
+			return err
+		}
+		defer socks5Listener.Close()
+	}
+	if httpAddress != "" {
+		if httpListener, err = net.Listen("tcp", httpAddress); err != nil {
+			return err
+		}
+		defer httpListener.Close()
+	}
+
+	tunnel, err := OpenTunnel(ctx, flags, offline, "")
+	if err != nil {
+		return err
+	}
+	defer tunnel.Close()
+	network := proxy.NewNetstackNetwork(tunnel.Net)
+
+	// the first to stop, e.g. on interrupt, stops the others
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	results := make(chan error, 3)
+	running := 1
+	go func() { results <- MonitorHandshake(ctx, tunnel, handshakeTimeout) }()
+	if socks5Listener != nil {
+		log.Println("SOCKS5 proxy listening on", socks5Listener.Addr())
+		running++
+		go func() {
+			results <- errors.WithMessage(proxy.NewSOCKS5Server(network).Serve(ctx, socks5Listener), "SOCKS5 proxy")
+		}()
+	}
+	if httpListener != nil {
+		log.Println("HTTP proxy listening on", httpListener.Addr())
+		running++
+		go func() {
+			results <- errors.WithMessage(proxy.NewHTTPServer(network).Serve(ctx, httpListener), "HTTP proxy")
+		}()
+	}
+	err = <-results
+	cancel()
+	for i := 1; i < running; i++ {
+		<-results
+	}
+	return err
+}
@@ -0,0 +1,101 @@
+package proxy
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/ViRb3/wgcf/v2/config"
+	"github.com/ViRb3/wgcf/v2/wireguard/wgtest"
+	"github.com/spf13/viper"
+)
+
+func getFreeAddress(t *testing.T) string {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	return listener.Addr().String()
+}
+
+func TestProxyOffline(t *testing.T) {
+	peer, err := wgtest.NewPeer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer peer.Close()
+	listener, err := peer.Net.ListenTCPAddrPort(netip.AddrPortFrom(wgtest.PeerAddress, 80))
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("hello"))
+	})}
+	go func() { _ = server.Serve(listener) }()
+	defer server.Close()
+
+	// the account and its cached device configuration point at the peer
+	viper.Reset()
+	for key, value := range map[string]string{
+		config.DeviceId:      "device",
+		config.AccessToken:   "token",
+		config.PrivateKey:    peer.ClientPrivateKey,
+		config.AddressV4:     wgtest.ClientAddress.String(),
+		config.AddressV6:     "fd00::2",
+		config.PeerPublicKey: peer.PublicKey,
+		config.EndpointHost:  peer.Endpoint,
+		config.CachedAt:      time.Now().UTC().Format(time.RFC3339),
+		config.ProfileIPMode: "v4",
+		config.ProfileDNS:    "none",
+	} {
+		viper.Set(key, value)
+	}
+	offline = true
+	socks5Address = getFreeAddress(t)
+	httpAddress = getFreeAddress(t)
+	defer func() {
+		offline = false
+		socks5Address = ""
+		httpAddress = ""
+	}()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- runProxy(ctx, Cmd.PersistentFlags()) }()
+
+	httpProxyURL, _ := url.Parse("http://" + httpAddress)
+	socks5ProxyURL, _ := url.Parse("socks5://" + socks5Address)
+	for _, proxyURL := range []*url.URL{httpProxyURL, socks5ProxyURL} {
+		client := &http.Client{Transport: &http.Transport{Proxy: http.ProxyURL(proxyURL)}, Timeout: 5 * time.Second}
+		var body []byte
+		// the proxy is ready once the tunnel is up
+		for attempt := 0; ; attempt++ {
+			response, err := client.Get("http://" + wgtest.PeerAddress.String() + "/")
+			if err == nil {
+				body, err = io.ReadAll(response.Body)
+				response.Body.Close()
+			}
+			if err == nil || attempt == 50 {
+				if err != nil {
+					t.Fatal(err)
+				}
+				break
+			}
+			time.Sleep(100 * time.Millisecond)
+		}
+		if string(body) != "hello" {
+			t.Fatalf("unexpected response through %s: %q", proxyURL, body)
+		}
+	}
+
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+}
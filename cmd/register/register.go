@@ -1,13 +1,12 @@
 package register
 
 import (
+	"context"
 	"fmt"
 	"log"
 
-	"github.com/ViRb3/wgcf/v2/cloudflare"
 	. "github.com/ViRb3/wgcf/v2/cmd/shared"
 	"github.com/ViRb3/wgcf/v2/config"
-	"github.com/ViRb3/wgcf/v2/util"
 	"github.com/ViRb3/wgcf/v2/wireguard"
 	"github.com/manifoldco/promptui"
 	"github.com/pkg/errors"
@@ -19,15 +18,18 @@ var deviceName string
 var deviceModel string
 var existingKey string
 var acceptedTOS = false
+var resume bool
 var shortMsg = "Registers a new Cloudflare Warp device and creates a new account, preparing it for connection"
 
 var Cmd = &cobra.Command{
 	Use:   "register",
 	Short: shortMsg,
-	Long:  FormatMessage(shortMsg, ``),
+	Long: FormatMessage(shortMsg, `
+The config file is only written once the device is fully set up. If the registration is interrupted,
+finish it with --resume instead of registering another device.`),
 	Run: func(cmd *cobra.Command, args []string) {
-		if err := registerAccount(); err != nil {
-			log.Fatal(util.GetErrorMessage(err))
+		if err := registerAccount(cmd.Context()); err != nil {
+			Fatal(err)
 		}
 	},
 }
@@ -37,62 +39,129 @@ func init() {
 	Cmd.PersistentFlags().StringVarP(&deviceModel, "model", "m", "PC", "Device model displayed under the 1.1.1.1 app")
 	Cmd.PersistentFlags().StringVarP(&existingKey, "key", "k", "", "Base64 private key used to authenticate your device over WireGuard (defaults to random)")
 	Cmd.PersistentFlags().BoolVar(&acceptedTOS, "accept-tos", false, "Accept Cloudflare's Terms of Service non-interactively")
+	Cmd.PersistentFlags().BoolVar(&resume, "resume", false, "Finish a partially completed registration")
 }
 
-func registerAccount() error {
+func registerAccount(ctx context.Context) error {
 	if IsConfigValidAccount() {
 		return errors.New("existing account detected")
 	}
-	if accepted, err := checkTOS(); err != nil || !accepted {
-		return err
+	cfg, err := LoadPendingRegistration()
+	if err != nil {
+		return errors.WithMessage(err, "failed to read pending registration")
+	}
+	if resume && cfg == nil {
+		return errors.New("no partially completed registration found")
+	} else if !resume && cfg != nil {
+		return errors.Errorf("partially completed registration found in %s, finish it with --resume", GetPendingRegistrationFile())
 	}
 
-	var privateKey *wireguard.Key
-	var err error
-
-	if existingKey != "" {
-		privateKey, err = wireguard.NewKey(existingKey)
+	if cfg == nil {
+		if accepted, err := checkTOS(); err != nil || !accepted {
+			return err
+		}
+		if cfg, err = createDevice(ctx, nil); err != nil {
+			return err
+		}
+	} else if cfg.DeviceId == "" {
+		log.Println("Resuming registration, registering the device with the saved private key")
+		if cfg, err = createDevice(ctx, cfg); err != nil {
+			return err
+		}
 	} else {
-		privateKey, err = wireguard.NewPrivateKey()
+		log.Println("Resuming registration of device", cfg.DeviceId)
 	}
+
+	client := CreateClient(cfg)
+	nameDevice := true
+	if resume {
+		// the interrupted registration may have named the device already
+		boundDevice, err := client.GetSourceBoundDevice(ctx)
+		if err != nil {
+			return err
+		}
+		nameDevice = boundDevice.Name == nil || (deviceName != "" && deviceName != *boundDevice.Name)
+	}
+	if nameDevice {
+		if _, err := SetDeviceName(ctx, client, deviceName); err != nil {
+			return err
+		}
+	}
+	thisDevice, err := client.GetSourceDevice(ctx)
 	if err != nil {
 		return err
 	}
-
-	device, err := cloudflare.Register(privateKey.Public(), deviceModel)
+	boundDevice, err := client.UpdateSourceBoundDeviceActive(ctx, true)
 	if err != nil {
 		return err
 	}
+	if !boundDevice.Active {
+		return errors.New("failed to activate device")
+	}
 
-	viper.Set(config.PrivateKey, privateKey.String())
-	viper.Set(config.DeviceId, device.Id)
-	viper.Set(config.AccessToken, device.Token)
-	viper.Set(config.LicenseKey, device.Account.License)
-	if err := viper.WriteConfig(); err != nil {
+	// only now the account is complete, so it's safe to save
+	SetAccountValue(config.PrivateKey, cfg.PrivateKey)
+	SetAccountValue(config.DeviceId, cfg.DeviceId)
+	SetAccountValue(config.AccessToken, cfg.AccessToken)
+	SetAccountValue(config.LicenseKey, thisDevice.Account.License)
+	CacheDeviceConfig(thisDevice)
+	if AccountName != "" && viper.GetString(config.DefaultAccount) == "" && !AccountExists("") {
+		// make the first named account the default, so it's used without --account
+		SetConfigValue(config.DefaultAccount, AccountName)
+	}
+	if err := WriteConfigAtomic(); err != nil {
 		return err
 	}
-
-	ctx := CreateContext()
-	_, err = SetDeviceName(ctx, deviceName)
-	if err != nil {
+	if err := RemovePendingRegistration(); err != nil {
 		return err
 	}
-	thisDevice, err := cloudflare.GetSourceDevice(ctx)
-	if err != nil {
+
+	if err := PrintOutput(NewDeviceData(thisDevice, boundDevice)); err != nil {
 		return err
 	}
+	log.Println("Successfully created Cloudflare Warp account")
+	return nil
+}
 
-	boundDevice, err := cloudflare.UpdateSourceBoundDeviceActive(ctx, true)
+// Registers a new device, and stages its credentials until the registration is complete.
+// The private key is staged before registering, so it isn't lost if the registration is interrupted.
+func createDevice(ctx context.Context, pending *config.Context) (*config.Context, error) {
+	var privateKey *wireguard.Key
+	var err error
+
+	if pending != nil {
+		privateKey, err = wireguard.NewKey(pending.PrivateKey)
+	} else if existingKey != "" {
+		privateKey, err = wireguard.NewKey(existingKey)
+	} else {
+		privateKey, err = wireguard.NewPrivateKey()
+	}
 	if err != nil {
-		return err
+		return nil, err
 	}
-	if !boundDevice.Active {
-		return errors.New("failed to activate device")
+	if err := SavePendingRegistration(&config.Context{PrivateKey: privateKey.String()}); err != nil {
+		return nil, errors.WithMessage(err, "failed to save pending registration")
 	}
 
-	PrintDeviceData(thisDevice, boundDevice)
-	log.Println("Successfully created Cloudflare Warp account")
-	return nil
+	device, err := CreateClient(nil).Register(ctx, privateKey.Public(), deviceModel)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg := &config.Context{
+		DeviceId:    device.Id,
+		AccessToken: device.Token,
+		PrivateKey:  privateKey.String(),
+		LicenseKey:  device.Account.License,
+	}
+	if err := SavePendingRegistration(cfg); err != nil {
+		SetAccountValue(config.PrivateKey, cfg.PrivateKey)
+		SetAccountValue(config.DeviceId, cfg.DeviceId)
+		SetAccountValue(config.AccessToken, cfg.AccessToken)
+		SetAccountValue(config.LicenseKey, cfg.LicenseKey)
+		return nil, SaveRecoveryConfig("registration", errors.WithMessage(err, "failed to save pending registration"))
+	}
+	return cfg, nil
 }
 
 func checkTOS() (bool, error) {
@@ -0,0 +1,141 @@
+package register
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"testing"
+
+	"github.com/ViRb3/wgcf/v2/cloudflare/cftest"
+	"github.com/ViRb3/wgcf/v2/cmd/shared"
+	"github.com/ViRb3/wgcf/v2/cmd/shared/sharedtest"
+	"github.com/ViRb3/wgcf/v2/config"
+	"github.com/ViRb3/wgcf/v2/wireguard"
+	"github.com/spf13/viper"
+)
+
+func TestResumeInterruptedRegistration(t *testing.T) {
+	server := cftest.NewServer()
+	defer server.Close()
+	configFile := sharedtest.Setup(t, server)
+	acceptedTOS = true
+	ctx := context.Background()
+
+	// naming the device fails after it was registered
+	server.InjectFailure(cftest.Failure{Route: cftest.RouteUpdateBoundDevice, StatusCode: http.StatusBadRequest, Times: 1})
+	if err := registerAccount(ctx); err == nil {
+		t.Fatal("expected registration to fail")
+	}
+	if _, err := os.Stat(configFile); !os.IsNotExist(err) {
+		t.Fatal("config must not be written before the device is activated")
+	}
+	if shared.IsConfigValidAccount() {
+		t.Fatal("config must not contain the incomplete account")
+	}
+	pending, err := shared.LoadPendingRegistration()
+	if err != nil || pending == nil {
+		t.Fatalf("expected pending registration, got %v", err)
+	}
+
+	if err := registerAccount(ctx); err == nil {
+		t.Fatal("expected registration without --resume to fail")
+	}
+
+	resume = true
+	defer func() { resume = false }()
+	if err := registerAccount(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if server.Requests(cftest.RouteRegister) != 1 {
+		t.Fatal("resuming must not register another device")
+	}
+	device := server.Device(pending.DeviceId)
+	if !device.Active || device.Name == nil {
+		t.Fatalf("device was not completed: %+v", device)
+	}
+
+	viper.Reset()
+	viper.SetConfigFile(configFile)
+	if err := viper.ReadInConfig(); err != nil {
+		t.Fatal(err)
+	}
+	if viper.GetString(config.DeviceId) != pending.DeviceId || viper.GetString(config.AccessToken) != pending.AccessToken {
+		t.Fatal("config does not contain the registered account")
+	}
+	if pending, err := shared.LoadPendingRegistration(); err != nil || pending != nil {
+		t.Fatal("pending registration was not removed")
+	}
+}
+
+func TestRegisterAlwaysNamesNewDevice(t *testing.T) {
+	server := cftest.NewServer()
+	defer server.Close()
+	sharedtest.Setup(t, server)
+	acceptedTOS = true
+
+	if err := registerAccount(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	// a fresh device is named without checking the name returned by the API, only --resume checks it
+	if server.Requests(cftest.RouteGetBoundDevices) != 0 || server.Requests(cftest.RouteUpdateBoundDevice) != 2 {
+		t.Fatal("expected the device to be named, then activated")
+	}
+	if device := server.Device(viper.GetString(config.DeviceId)); device.Name == nil || *device.Name == "" {
+		t.Fatalf("device was not named: %+v", device)
+	}
+}
+
+func TestResumeRegistrationWithSavedKey(t *testing.T) {
+	server := cftest.NewServer()
+	defer server.Close()
+	sharedtest.Setup(t, server)
+	acceptedTOS = true
+	ctx := context.Background()
+
+	server.InjectFailure(cftest.Failure{Route: cftest.RouteRegister, StatusCode: http.StatusBadRequest, Times: 1})
+	if err := registerAccount(ctx); err == nil {
+		t.Fatal("expected registration to fail")
+	}
+	pending, err := shared.LoadPendingRegistration()
+	if err != nil || pending == nil || pending.PrivateKey == "" || pending.DeviceId != "" {
+		t.Fatalf("expected the private key to be saved before registering, got %+v, %v", pending, err)
+	}
+	privateKey, err := wireguard.NewKey(pending.PrivateKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resume = true
+	defer func() { resume = false }()
+	if err := registerAccount(ctx); err != nil {
+		t.Fatal(err)
+	}
+	device := server.Device(viper.GetString(config.DeviceId))
+	if device == nil || device.Key != privateKey.Public().String() || !device.Active {
+		t.Fatalf("the device was not registered with the saved key: %+v", device)
+	}
+}
+
+func TestPendingRegistrationIsEncrypted(t *testing.T) {
+	server := cftest.NewServer()
+	defer server.Close()
+	sharedtest.Setup(t, server)
+	t.Setenv(shared.PassphraseEnv, "correct horse battery staple")
+	acceptedTOS = true
+
+	server.InjectFailure(cftest.Failure{Route: cftest.RouteUpdateBoundDevice, StatusCode: http.StatusBadRequest, Times: 1})
+	if err := registerAccount(context.Background()); err == nil {
+		t.Fatal("expected registration to fail")
+	}
+	data, err := os.ReadFile(shared.GetPendingRegistrationFile())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !config.IsEncrypted(data) {
+		t.Fatal("the pending registration must be encrypted when a passphrase is given")
+	}
+	pending, err := shared.LoadPendingRegistration()
+	if err != nil || pending == nil || server.Device(pending.DeviceId) == nil {
+		t.Fatalf("expected pending registration, got %v", err)
+	}
+}
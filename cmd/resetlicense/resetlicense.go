@@ -0,0 +1,55 @@
+package resetlicense
+
+import (
+	"context"
+	"log"
+
+	. "github.com/ViRb3/wgcf/v2/cmd/shared"
+	"github.com/ViRb3/wgcf/v2/config"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+var shortMsg = "Replaces the license key of the current Cloudflare Warp account with a new one"
+
+var Cmd = &cobra.Command{
+	Use:   "reset-license",
+	Short: shortMsg,
+	Long: FormatMessage(shortMsg, `
+The old license key is invalidated, so other devices can no longer use it to bind to this account.
+Devices already bound to the account stay bound.`),
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := resetLicense(cmd.Context()); err != nil {
+			Fatal(err)
+		}
+	},
+}
+
+func resetLicense(ctx context.Context) error {
+	if !IsConfigValidAccount() {
+		return errors.New("no account detected")
+	}
+
+	cfg := CreateContext()
+	client := CreateClient(cfg)
+	result, err := client.ResetLicense(ctx)
+	if err != nil {
+		return err
+	}
+	if result.License == "" || result.License == cfg.LicenseKey {
+		return errors.New("failed to reset license key")
+	}
+
+	SetAccountValue(config.LicenseKey, result.License)
+	if err := WriteConfigAtomic(); err != nil {
+		// the old license key is no longer valid, so don't lose the new one
+		return SaveRecoveryConfig("license key", err)
+	}
+
+	log.Println("=======================================")
+	log.Printf("%-13s : %s\n", "Old license", cfg.LicenseKey)
+	log.Printf("%-13s : %s\n", "New license", result.License)
+	log.Println("=======================================")
+	log.Println("Successfully reset license key")
+	return nil
+}
@@ -1,17 +1,33 @@
 package cmd
 
 import (
+	"context"
 	"errors"
+	"io/fs"
 	"log"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
 
+	"github.com/ViRb3/wgcf/v2/cloudflare"
+	"github.com/ViRb3/wgcf/v2/cmd/account"
+	"github.com/ViRb3/wgcf/v2/cmd/clientconfig"
+	"github.com/ViRb3/wgcf/v2/cmd/configfile"
+	"github.com/ViRb3/wgcf/v2/cmd/connect"
+	"github.com/ViRb3/wgcf/v2/cmd/devices"
+	"github.com/ViRb3/wgcf/v2/cmd/endpoint"
 	"github.com/ViRb3/wgcf/v2/cmd/generate"
+	"github.com/ViRb3/wgcf/v2/cmd/proxy"
 	"github.com/ViRb3/wgcf/v2/cmd/register"
+	"github.com/ViRb3/wgcf/v2/cmd/resetlicense"
+	"github.com/ViRb3/wgcf/v2/cmd/rotatekey"
 	. "github.com/ViRb3/wgcf/v2/cmd/shared"
 	"github.com/ViRb3/wgcf/v2/cmd/status"
 	"github.com/ViRb3/wgcf/v2/cmd/trace"
 	"github.com/ViRb3/wgcf/v2/cmd/update"
 	"github.com/ViRb3/wgcf/v2/config"
-	"github.com/ViRb3/wgcf/v2/util"
 
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
@@ -26,44 +42,90 @@ var RootCmd = &cobra.Command{
 wgcf is a utility for Cloudflare Warp that allows you to create and
 manage accounts, assign license keys, and generate WireGuard profiles.
 Made by Victor (@ViRb3). Project website: https://github.com/ViRb3/wgcf`),
+	PersistentPreRun: func(cmd *cobra.Command, args []string) {
+		if timeout > 0 {
+			ctx, cancel := context.WithTimeout(cmd.Context(), timeout)
+			cmd.SetContext(ctx)
+			cancelTimeout = cancel
+		}
+	},
 	Run: func(cmd *cobra.Command, args []string) {
 		if err := cmd.Help(); err != nil {
-			log.Fatal(util.GetErrorMessage(err))
+			Fatal(err)
 		}
 	},
 }
 
+var timeout time.Duration
+var cancelTimeout context.CancelFunc = func() {}
+
+// Commands get a context which is cancelled on interrupt, or when the --timeout expires.
 func Execute() error {
-	return RootCmd.Execute()
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	defer func() { cancelTimeout() }()
+	return RootCmd.ExecuteContext(ctx)
 }
 
 func init() {
 	cobra.OnInitialize(initConfig)
 	RootCmd.PersistentFlags().StringVar(&cfgFile, "config", "wgcf-account.toml", "Configuration file")
+	RootCmd.PersistentFlags().StringVar(&PassphraseFile, "passphrase-file", "", "File containing the passphrase of an encrypted configuration file (defaults to $"+PassphraseEnv+", then prompting)")
+	RootCmd.PersistentFlags().StringVarP(&AccountName, "account", "a", "", "Named account to use from the configuration file (defaults to the default account)")
+	RootCmd.PersistentFlags().DurationVar(&timeout, "timeout", 0, "Maximum duration of the whole command, e.g. 30s (defaults to none)")
+	RootCmd.PersistentFlags().IntVar(&cloudflare.DefaultRetryTransport.MaxAttempts, "max-attempts", cloudflare.DefaultRetryTransport.MaxAttempts, "Maximum number of attempts for each API request, retrying transient failures")
+	RootCmd.PersistentFlags().String(flagName(config.ApiUrl), "", "API endpoint (defaults to "+cloudflare.ApiUrl+")")
+	RootCmd.PersistentFlags().String(flagName(config.ApiVersion), "", "API version (defaults to "+cloudflare.ApiVersion+")")
+	RootCmd.PersistentFlags().String(flagName(config.ClientIdentity), "", "Client identity preset setting the headers, device type and TLS parameters, one of: "+strings.Join(cloudflare.GetClientIdentityNames(), ", ")+" (defaults to "+cloudflare.DefaultClientIdentity+")")
+	RootCmd.PersistentFlags().String(flagName(config.UserAgent), "", "User-Agent header (defaults to the client identity's)")
+	RootCmd.PersistentFlags().String(flagName(config.ClientVersion), "", "CF-Client-Version header (defaults to the client identity's)")
+	RootCmd.PersistentFlags().String(flagName(config.DeviceType), "", "Device type sent when registering (defaults to the client identity's)")
+	RootCmd.PersistentFlags().StringVar(&RecordFile, "record", "", "Record the API requests and responses into a cassette file, with secrets redacted")
+	_ = RootCmd.PersistentFlags().MarkHidden("record")
+	RootCmd.PersistentFlags().StringVarP(&OutputFormat, "output", "o", OutputTable, "Output format, one of: table, json, yaml")
 	RootCmd.AddCommand(register.Cmd)
 	RootCmd.AddCommand(update.Cmd)
 	RootCmd.AddCommand(generate.Cmd)
+	RootCmd.AddCommand(connect.Cmd)
+	RootCmd.AddCommand(proxy.Cmd)
+	RootCmd.AddCommand(endpoint.Cmd)
 	RootCmd.AddCommand(status.Cmd)
 	RootCmd.AddCommand(trace.Cmd)
+	RootCmd.AddCommand(rotatekey.Cmd)
+	RootCmd.AddCommand(resetlicense.Cmd)
+	RootCmd.AddCommand(clientconfig.Cmd)
+	RootCmd.AddCommand(devices.Cmd)
+	RootCmd.AddCommand(account.Cmd)
+	RootCmd.AddCommand(configfile.Cmd)
 }
 
-var unsupportedConfigError viper.UnsupportedConfigError
-
 func initConfig() {
-	initConfigDefaults()
-	viper.SetConfigFile(cfgFile)
-	viper.SetEnvPrefix("WGCF")
-	viper.AutomaticEnv()
-	if err := viper.ReadInConfig(); errors.As(err, &unsupportedConfigError) {
+	if !IsValidOutputFormat(OutputFormat) {
+		log.Fatal("unsupported output format: " + OutputFormat)
+	}
+	if AccountName != "" && !IsValidAccountName(AccountName) {
+		log.Fatal("invalid account name: " + AccountName)
+	}
+	InitConfig(cfgFile)
+	if err := ReadConfig(); err != nil && !errors.Is(err, fs.ErrNotExist) {
 		log.Fatal(err)
 	} else {
 		log.Println("Using config file:", viper.ConfigFileUsed())
 	}
+	initClientOptions()
 }
 
-func initConfigDefaults() {
-	viper.SetDefault(config.DeviceId, "")
-	viper.SetDefault(config.AccessToken, "")
-	viper.SetDefault(config.PrivateKey, "")
-	viper.SetDefault(config.LicenseKey, "")
+func flagName(configKey string) string {
+	return strings.ReplaceAll(configKey, "_", "-")
+}
+
+func initClientOptions() {
+	for _, key := range ClientSettingKeys {
+		if flag := RootCmd.PersistentFlags().Lookup(flagName(key)); flag != nil && flag.Changed {
+			SettingFlags[key] = flag.Value.String()
+		}
+	}
+	if err := InitClientOptions(); err != nil {
+		log.Fatal(err)
+	}
 }
@@ -0,0 +1,292 @@
+package cmd
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ViRb3/wgcf/v2/cloudflare/cftest"
+	"github.com/ViRb3/wgcf/v2/cmd/shared"
+	"github.com/ViRb3/wgcf/v2/config"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+)
+
+// Runs wgcf against the mock server, with a config file in dir.
+func execute(t *testing.T, server *cftest.Server, dir string, args ...string) {
+	viper.Reset()
+	resetFlags(RootCmd)
+	args = append(args, "--config", filepath.Join(dir, "wgcf-account.toml"), "--api-url", server.URL)
+	RootCmd.SetArgs(args)
+	if err := RootCmd.ExecuteContext(context.Background()); err != nil {
+		t.Fatalf("wgcf %s: %v", strings.Join(args, " "), err)
+	}
+}
+
+// flags keep their values between executions, like any package variable
+func resetFlags(cmd *cobra.Command) {
+	cmd.PersistentFlags().VisitAll(func(flag *pflag.Flag) {
+		if sliceValue, ok := flag.Value.(pflag.SliceValue); ok {
+			_ = sliceValue.Replace(nil)
+		} else {
+			_ = flag.Value.Set(flag.DefValue)
+		}
+		flag.Changed = false
+	})
+	for _, child := range cmd.Commands() {
+		resetFlags(child)
+	}
+}
+
+func TestRegisterGenerateStatus(t *testing.T) {
+	server := cftest.NewServer()
+	defer server.Close()
+	dir := t.TempDir()
+
+	execute(t, server, dir, "register", "--accept-tos", "--name", "test-device")
+	deviceId := viper.GetString(config.DeviceId)
+	device := server.Device(deviceId)
+	if device == nil {
+		t.Fatal("device was not registered")
+	}
+	if !device.Active || device.Name == nil || *device.Name != "test-device" {
+		t.Fatalf("unexpected device %+v", device)
+	}
+	if viper.GetString(config.AccessToken) != device.Token {
+		t.Fatal("access token was not saved")
+	}
+
+	profileFile := filepath.Join(dir, "wgcf-profile.conf")
+	execute(t, server, dir, "generate", "--profile", profileFile)
+	profile, err := os.ReadFile(profileFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, expected := range []string{
+		"PrivateKey = " + viper.GetString(config.PrivateKey),
+		"PublicKey = " + cftest.PeerPublicKey,
+		"Address = " + device.AddressV4 + "/32",
+		"Endpoint = " + cftest.EndpointHost,
+	} {
+		if !strings.Contains(string(profile), expected) {
+			t.Fatalf("profile does not contain %q:\n%s", expected, profile)
+		}
+	}
+
+	// the device configuration is unchanged, so the config isn't rewritten
+	configFile := filepath.Join(dir, "wgcf-account.toml")
+	before, err := os.Stat(configFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	execute(t, server, dir, "status")
+	if requests := server.Requests(cftest.RouteGetSourceDevice); requests != 3 {
+		t.Fatalf("expected 3 device requests, got %d", requests)
+	}
+	after, err := os.Stat(configFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !os.SameFile(before, after) {
+		t.Fatal("status rewrote the unchanged config")
+	}
+}
+
+func TestUpdateBindsLicenseKey(t *testing.T) {
+	server := cftest.NewServer()
+	defer server.Close()
+	dir := t.TempDir()
+
+	execute(t, server, dir, "register", "--accept-tos")
+	deviceId := viper.GetString(config.DeviceId)
+
+	license := server.CreatePlusAccount(1)
+	shared.SetAccountValue(config.LicenseKey, license)
+	if err := shared.WriteConfigAtomic(); err != nil {
+		t.Fatal(err)
+	}
+
+	execute(t, server, dir, "update", "--name", "updated-device")
+	account := server.AccountOf(deviceId)
+	if account.License != license || account.AccountType != "unlimited" {
+		t.Fatalf("device was not bound to the new account, got %+v", account)
+	}
+	device := server.Device(deviceId)
+	if device.Name == nil || *device.Name != "updated-device" {
+		t.Fatalf("unexpected device name %v", device.Name)
+	}
+	if viper.GetString(config.LicenseKey) != license {
+		t.Fatal("license key was not kept")
+	}
+}
+
+func TestRecordCassette(t *testing.T) {
+	server := cftest.NewServer()
+	defer server.Close()
+	dir := t.TempDir()
+	cassetteFile := filepath.Join(dir, "session.jsonl")
+
+	execute(t, server, dir, "register", "--accept-tos", "--record", cassetteFile)
+	data, err := os.ReadFile(cassetteFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), viper.GetString(config.AccessToken)) {
+		t.Fatal("cassette contains the access token")
+	}
+	if lines := strings.Count(string(data), "\n"); lines != 4 {
+		t.Fatalf("expected 4 recorded requests, got %d", lines)
+	}
+}
+
+func TestGenerateOffline(t *testing.T) {
+	server := cftest.NewServer()
+	dir := t.TempDir()
+	execute(t, server, dir, "register", "--accept-tos")
+	device := server.Device(viper.GetString(config.DeviceId))
+	server.Close()
+
+	profileFile := filepath.Join(dir, "wgcf-profile.conf")
+	execute(t, server, dir, "generate", "--offline", "--profile", profileFile)
+	profile, err := os.ReadFile(profileFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, expected := range []string{
+		"PublicKey = " + cftest.PeerPublicKey,
+		"Address = " + device.AddressV4 + "/32, " + device.AddressV6 + "/128",
+		"Endpoint = " + cftest.EndpointHost,
+	} {
+		if !strings.Contains(string(profile), expected) {
+			t.Fatalf("profile does not contain %q:\n%s", expected, profile)
+		}
+	}
+}
+
+func TestGenerateFormat(t *testing.T) {
+	server := cftest.NewServer()
+	defer server.Close()
+	dir := t.TempDir()
+	execute(t, server, dir, "register", "--accept-tos")
+
+	execute(t, server, dir, "generate", "--format", "networkd", "--profile", filepath.Join(dir, "wgcf.conf"))
+	for _, file := range []string{"wgcf.netdev", "wgcf.network"} {
+		if _, err := os.Stat(filepath.Join(dir, file)); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestGenerateProfileSettings(t *testing.T) {
+	server := cftest.NewServer()
+	defer server.Close()
+	dir := t.TempDir()
+	execute(t, server, dir, "register", "--accept-tos")
+
+	// settings from the config are overridden by flags
+	configFile, err := os.OpenFile(filepath.Join(dir, "wgcf-account.toml"), os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = configFile.WriteString("profile_table = \"off\"\nprofile_mtu = 1300\n")
+	configFile.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	profileFile := filepath.Join(dir, "wgcf-profile.conf")
+	execute(t, server, dir, "generate", "--profile", profileFile, "--mtu", "1420", "--dns", "none",
+		"--keepalive", "25", "--ip-mode", "v4", "--exclude", "10.0.0.0/8", "--post-up", "echo a, b")
+	profile, err := os.ReadFile(profileFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, expected := range []string{"MTU = 1420", "Table = off", "PersistentKeepalive = 25", "PostUp = echo a, b", "AllowedIPs = 0.0.0.0/5,"} {
+		if !strings.Contains(string(profile), expected) {
+			t.Fatalf("profile does not contain %q:\n%s", expected, profile)
+		}
+	}
+	for _, unexpected := range []string{"DNS", "::/0", "/128"} {
+		if strings.Contains(string(profile), unexpected) {
+			t.Fatalf("profile contains %q:\n%s", unexpected, profile)
+		}
+	}
+}
+
+func TestGenerateTemplate(t *testing.T) {
+	server := cftest.NewServer()
+	defer server.Close()
+	dir := t.TempDir()
+	execute(t, server, dir, "register", "--accept-tos")
+
+	templateFile := filepath.Join(dir, "profile.tmpl")
+	if err := os.WriteFile(templateFile, []byte("{{ .PublicKey }} {{ .EndpointV4 }} {{ json .GetReserved }}\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	profileFile := filepath.Join(dir, "profile.txt")
+	execute(t, server, dir, "generate", "--template", templateFile, "--profile", profileFile)
+	profile, err := os.ReadFile(profileFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := cftest.PeerPublicKey + " " + cftest.EndpointV4 + " [1,2,3]\n"
+	if string(profile) != expected {
+		t.Fatalf("expected %q, got %q", expected, profile)
+	}
+}
+
+func TestGenerateEndpoint(t *testing.T) {
+	server := cftest.NewServer()
+	defer server.Close()
+	dir := t.TempDir()
+	execute(t, server, dir, "register", "--accept-tos")
+
+	profileFile := filepath.Join(dir, "wgcf-profile.conf")
+	execute(t, server, dir, "generate", "--profile", profileFile, "--endpoint", "162.159.192.1:500")
+	profile, err := os.ReadFile(profileFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(profile), "Endpoint = 162.159.192.1:500\n") {
+		t.Fatalf("profile does not use the endpoint:\n%s", profile)
+	}
+
+	// the literal address keeps the port of the host
+	execute(t, server, dir, "generate", "--profile", profileFile, "--endpoint-mode", "v6")
+	if profile, err = os.ReadFile(profileFile); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(profile), "Endpoint = [2606:4700:d0::a29f:c001]:2408\n") {
+		t.Fatalf("profile does not use the IPv6 endpoint:\n%s", profile)
+	}
+}
+
+func TestEnvironmentCredentialsAreNotWritten(t *testing.T) {
+	server := cftest.NewServer()
+	defer server.Close()
+	execute(t, server, t.TempDir(), "register", "--accept-tos")
+	credentials := map[string]string{}
+	for _, key := range []string{config.DeviceId, config.AccessToken, config.PrivateKey} {
+		credentials[key] = viper.GetString(key)
+		t.Setenv("WGCF_"+strings.ToUpper(key), credentials[key])
+	}
+
+	dir := t.TempDir()
+	execute(t, server, dir, "status")
+	file := viper.New()
+	file.SetConfigFile(filepath.Join(dir, "wgcf-account.toml"))
+	if err := file.ReadInConfig(); err != nil {
+		t.Fatal(err)
+	}
+	for key := range credentials {
+		if file.IsSet(key) {
+			t.Fatalf("%s from the environment was written to the config", key)
+		}
+	}
+	if file.GetString(config.PeerPublicKey) != cftest.PeerPublicKey {
+		t.Fatal("the device configuration was not cached")
+	}
+}
@@ -0,0 +1,90 @@
+package rotatekey
+
+import (
+	"context"
+	"log"
+	"strings"
+
+	. "github.com/ViRb3/wgcf/v2/cmd/shared"
+	"github.com/ViRb3/wgcf/v2/config"
+	"github.com/ViRb3/wgcf/v2/wireguard"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+var profileFile string
+var shortMsg = "Replaces the private key of the current Cloudflare Warp device with a new one"
+
+var Cmd = &cobra.Command{
+	Use:   "rotate-key",
+	Short: shortMsg,
+	Long: FormatMessage(shortMsg, `
+The device and its bound account are kept, only the WireGuard key pair changes.
+Any previously generated profile stops working, regenerate it or pass --profile,
+which accepts the --format and --template of generate.`),
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := rotateKey(cmd.Context(), cmd.Flags()); err != nil {
+			Fatal(err)
+		}
+	},
+}
+
+func init() {
+	Cmd.PersistentFlags().StringVarP(&profileFile, "profile", "p", "", "WireGuard profile file to regenerate with the new key (defaults to none)")
+	AddFormatFlags(Cmd.PersistentFlags())
+}
+
+func rotateKey(ctx context.Context, flags *pflag.FlagSet) error {
+	if !IsConfigValidAccount() {
+		return errors.New("no account detected")
+	}
+
+	// invalid settings must fail before the key is rotated
+	var profileData *wireguard.ProfileData
+	var formatter wireguard.Formatter
+	var profilePath string
+	if profileFile != "" {
+		var err error
+		if profileData, err = GetProfileSettings(nil); err != nil {
+			return err
+		}
+		if formatter, profilePath, err = GetFormatter(flags, profileFile); err != nil {
+			return err
+		}
+	}
+
+	privateKey, err := wireguard.NewPrivateKey()
+	if err != nil {
+		return err
+	}
+
+	cfg := CreateContext()
+	client := CreateClient(cfg)
+	thisDevice, err := client.UpdateSourceDeviceKey(ctx, privateKey.Public())
+	if err != nil {
+		return err
+	}
+	if thisDevice.Key != privateKey.Public().String() {
+		return errors.New("failed to update device key")
+	}
+
+	SetAccountValue(config.PrivateKey, privateKey.String())
+	// so generate --offline and connect use the device configuration returned for the new key
+	CacheDeviceConfig(thisDevice)
+	if err := WriteConfigAtomic(); err != nil {
+		// the old key is no longer valid, so don't lose the new one
+		return SaveRecoveryConfig("private key", err)
+	}
+	log.Println("Successfully rotated device key")
+
+	if profileFile != "" {
+		SetProfileDevice(profileData, thisDevice, privateKey.String())
+		paths, err := SaveProfile(profileData, formatter, profilePath)
+		if err != nil {
+			return err
+		}
+		log.Println("Successfully generated WireGuard profile:", strings.Join(paths, ", "))
+	}
+	return nil
+}
@@ -0,0 +1,94 @@
+package rotatekey
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ViRb3/wgcf/v2/cloudflare/cftest"
+	"github.com/ViRb3/wgcf/v2/cmd/shared"
+	"github.com/ViRb3/wgcf/v2/cmd/shared/sharedtest"
+	"github.com/ViRb3/wgcf/v2/config"
+	"github.com/ViRb3/wgcf/v2/wireguard"
+	"github.com/spf13/viper"
+)
+
+func TestRotateKeySavesRecoveryConfig(t *testing.T) {
+	server := cftest.NewServer()
+	defer server.Close()
+	configFile := sharedtest.Setup(t, server)
+	device := sharedtest.Register(t)
+	privateKey := viper.GetString(config.PrivateKey)
+
+	// a directory in place of the config, so the config can't be written
+	if err := os.MkdirAll(filepath.Join(configFile, "dir"), 0700); err != nil {
+		t.Fatal(err)
+	}
+	err := rotateKey(context.Background(), Cmd.PersistentFlags())
+	if err == nil {
+		t.Fatal("expected saving the config to fail")
+	}
+	newKey := viper.GetString(config.PrivateKey)
+	if newKey == privateKey {
+		t.Fatal("the key was not rotated")
+	}
+	if strings.Contains(err.Error(), newKey) || !strings.Contains(err.Error(), shared.GetRecoveryConfigFile()) {
+		t.Fatalf("the error must point to the recovery file instead of containing the key: %v", err)
+	}
+
+	info, err := os.Stat(shared.GetRecoveryConfigFile())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Fatalf("unexpected recovery file mode %v", info.Mode())
+	}
+	recovery := viper.New()
+	recovery.SetConfigFile(shared.GetRecoveryConfigFile())
+	recovery.SetConfigType("toml")
+	if err := recovery.ReadInConfig(); err != nil {
+		t.Fatal(err)
+	}
+	recoveredKey, err := wireguard.NewKey(recovery.GetString(config.PrivateKey))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if recoveredKey.Public().String() != server.Device(device.Id).Key {
+		t.Fatal("the recovery file does not contain the registered key")
+	}
+}
+
+func TestRotateKeyProfileFormat(t *testing.T) {
+	server := cftest.NewServer()
+	defer server.Close()
+	configFile := sharedtest.Setup(t, server)
+	sharedtest.Register(t)
+
+	profileFile = filepath.Join(filepath.Dir(configFile), "wgcf-profile.conf")
+	defer func() { profileFile = "" }()
+	flags := Cmd.PersistentFlags()
+	if err := flags.Set("format", "sing-box"); err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		_ = flags.Set("format", wireguard.DefaultFormat)
+		flags.Lookup("format").Changed = false
+	}()
+
+	if err := rotateKey(context.Background(), flags); err != nil {
+		t.Fatal(err)
+	}
+	// the extension follows the format, like generate
+	profile, err := os.ReadFile(filepath.Join(filepath.Dir(configFile), "wgcf-profile.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(profile), `"private_key": "`+viper.GetString(config.PrivateKey)+`"`) {
+		t.Fatalf("profile does not contain the new key:\n%s", profile)
+	}
+	if shared.GetAccountValue(config.PeerPublicKey) != cftest.PeerPublicKey || shared.GetAccountValue(config.CachedAt) == "" {
+		t.Fatal("the device configuration was not cached")
+	}
+}
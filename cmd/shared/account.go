@@ -0,0 +1,96 @@
+package shared
+
+import (
+	"regexp"
+	"sort"
+
+	"github.com/ViRb3/wgcf/v2/config"
+	"github.com/spf13/viper"
+)
+
+// Set by the global --account flag.
+var AccountName string
+
+var accountNameRegex = regexp.MustCompile(`^[a-z0-9_-]+$`)
+
+// Names must be usable as a single TOML/viper key segment.
+func IsValidAccountName(name string) bool {
+	return accountNameRegex.MatchString(name)
+}
+
+// Returns the account selected by --account, falling back to the default account.
+// An empty name refers to the unnamed account stored in the top-level keys.
+func GetActiveAccountName() string {
+	if AccountName != "" {
+		return AccountName
+	}
+	return viper.GetString(config.DefaultAccount)
+}
+
+func accountKey(name string, key string) string {
+	if name == "" {
+		return key
+	}
+	return config.Accounts + "." + name + "." + key
+}
+
+func GetAccountValue(key string) string {
+	return GetNamedAccountValue(GetActiveAccountName(), key)
+}
+
+func SetAccountValue(key string, value string) {
+	SetNamedAccountValue(GetActiveAccountName(), key, value)
+}
+
+func GetNamedAccountValue(name string, key string) string {
+	return viper.GetString(accountKey(name, key))
+}
+
+func SetNamedAccountValue(name string, key string, value string) {
+	SetConfigValue(accountKey(name, key), value)
+}
+
+func GetAccountNames() []string {
+	var names []string
+	for name := range viper.GetStringMap(config.Accounts) {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func AccountExists(name string) bool {
+	if name == "" {
+		return GetNamedAccountValue(name, config.DeviceId) != ""
+	}
+	return viper.IsSet(config.Accounts + "." + name)
+}
+
+// viper can't unset keys, so the config is rebuilt without the account and then reloaded
+// into a fresh viper, as values set in memory would otherwise still shadow the removed ones
+func RemoveAccount(name string) error {
+	settings := fileSettings.AllSettings()
+	if name == "" {
+		for _, key := range append([]string{config.DeviceId, config.AccessToken, config.PrivateKey, config.LicenseKey}, config.CacheKeys...) {
+			delete(settings, key)
+		}
+	}
+	if accounts, ok := settings[config.Accounts].(map[string]interface{}); ok && name != "" {
+		delete(accounts, name)
+	}
+	if settings[config.DefaultAccount] == name {
+		delete(settings, config.DefaultAccount)
+	}
+
+	v := viper.New()
+	v.SetConfigFile(viper.ConfigFileUsed())
+	if err := v.MergeConfigMap(settings); err != nil {
+		return err
+	}
+	if err := writeConfigAtomic(v); err != nil {
+		return err
+	}
+	viper.Reset()
+	InitConfig(v.ConfigFileUsed())
+	return ReadConfig()
+}
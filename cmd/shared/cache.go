@@ -0,0 +1,74 @@
+package shared
+
+import (
+	"time"
+
+	"github.com/ViRb3/wgcf/v2/cloudflare"
+	"github.com/ViRb3/wgcf/v2/config"
+	"github.com/ViRb3/wgcf/v2/openapi"
+	"github.com/pkg/errors"
+)
+
+// Cached device configurations older than this may be outdated.
+const DeviceCacheMaxAge = 7 * 24 * time.Hour
+
+// An unchanged cache is only refreshed after this, so read-only commands rarely rewrite the config.
+const DeviceCacheRefreshAge = 24 * time.Hour
+
+// Caches the device configuration in the active account, and returns whether it changed,
+// in which case the config still needs to be written.
+func CacheDeviceConfig(thisDevice *cloudflare.Device) bool {
+	if len(thisDevice.Config.Peers) == 0 {
+		return false
+	}
+	peer := thisDevice.Config.Peers[0]
+	changed := false
+	for key, value := range map[string]string{
+		config.AddressV4:     thisDevice.Config.Interface.Addresses.V4,
+		config.AddressV6:     thisDevice.Config.Interface.Addresses.V6,
+		config.PeerPublicKey: peer.PublicKey,
+		config.EndpointHost:  peer.Endpoint.Host,
+		config.EndpointV4:    peer.Endpoint.V4,
+		config.EndpointV6:    peer.Endpoint.V6,
+		config.ClientId:      thisDevice.Config.ClientId,
+	} {
+		if GetAccountValue(key) != value {
+			SetAccountValue(key, value)
+			changed = true
+		}
+	}
+	// keeps the age of an unchanged cache from growing stale, without a write on every run
+	cachedAt, err := time.Parse(time.RFC3339, GetAccountValue(config.CachedAt))
+	if changed || err != nil || time.Since(cachedAt) > DeviceCacheRefreshAge {
+		SetAccountValue(config.CachedAt, time.Now().UTC().Format(time.RFC3339))
+		changed = true
+	}
+	return changed
+}
+
+// Returns the cached device configuration of the active account, and when it was cached.
+// Only the configuration of the device is set, not its details.
+func GetCachedDevice() (*cloudflare.Device, time.Time, error) {
+	if GetAccountValue(config.PeerPublicKey) == "" || GetAccountValue(config.EndpointHost) == "" {
+		return nil, time.Time{}, errors.New("no cached device configuration, run status or update once while online")
+	}
+	cachedAt, err := time.Parse(time.RFC3339, GetAccountValue(config.CachedAt))
+	if err != nil {
+		return nil, time.Time{}, errors.WithMessage(err, "invalid cached device configuration")
+	}
+	thisDevice := &cloudflare.Device{Id: GetAccountValue(config.DeviceId)}
+	thisDevice.Config.Interface.Addresses = openapi.GetSourceDevice200ResponseConfigInterfaceAddresses{
+		V4: GetAccountValue(config.AddressV4),
+		V6: GetAccountValue(config.AddressV6),
+	}
+	thisDevice.Config.Peers = []openapi.GetSourceDevice200ResponseConfigPeers{{
+		PublicKey: GetAccountValue(config.PeerPublicKey),
+		Endpoint: openapi.GetSourceDevice200ResponseConfigEndpoint{
+			Host: GetAccountValue(config.EndpointHost),
+			V4:   GetAccountValue(config.EndpointV4),
+			V6:   GetAccountValue(config.EndpointV6),
+		},
+	}}
+	thisDevice.Config.ClientId = GetAccountValue(config.ClientId)
+	return thisDevice, cachedAt, nil
+}
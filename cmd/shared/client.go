@@ -0,0 +1,81 @@
+package shared
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/ViRb3/wgcf/v2/cloudflare"
+	"github.com/ViRb3/wgcf/v2/cloudflare/cassette"
+	"github.com/ViRb3/wgcf/v2/config"
+	"github.com/pkg/errors"
+	"github.com/spf13/viper"
+)
+
+// Config keys which can be overridden by a global flag of the same name, with dashes instead of underscores.
+var ClientSettingKeys = []string{
+	config.ApiUrl,
+	config.ApiVersion,
+	config.ClientIdentity,
+	config.UserAgent,
+	config.ClientVersion,
+	config.DeviceType,
+}
+
+// Values of the global flags which were set, by config key. Set by the root command.
+var SettingFlags = map[string]string{}
+
+// Cassette file to record the API requests into. Set by the global --record flag.
+var RecordFile string
+
+var clientOptions *cloudflare.ClientOptions
+
+// flags take precedence over the environment, which takes precedence over the config
+func getSetting(key string) string {
+	if value, ok := SettingFlags[key]; ok {
+		return value
+	}
+	return viper.GetString(key)
+}
+
+// Resolves the API endpoint and client identity used by CreateClient.
+func InitClientOptions() error {
+	identityName := getSetting(config.ClientIdentity)
+	if identityName == "" {
+		identityName = cloudflare.DefaultClientIdentity
+	}
+	identity, ok := cloudflare.ClientIdentities[identityName]
+	if !ok {
+		return errors.Errorf("unknown client identity %s, must be one of: %s", identityName,
+			strings.Join(cloudflare.GetClientIdentityNames(), ", "))
+	}
+	if userAgent := getSetting(config.UserAgent); userAgent != "" {
+		identity.UserAgent = userAgent
+	}
+	if clientVersion := getSetting(config.ClientVersion); clientVersion != "" {
+		identity.ClientVersion = clientVersion
+	}
+	if deviceType := getSetting(config.DeviceType); deviceType != "" {
+		identity.DeviceType = deviceType
+	}
+
+	options := cloudflare.NewClientOptions(identity)
+	if apiUrl := getSetting(config.ApiUrl); apiUrl != "" {
+		options.BaseUrl = strings.TrimSuffix(apiUrl, "/")
+	}
+	if apiVersion := getSetting(config.ApiVersion); apiVersion != "" {
+		options.ApiVersion = apiVersion
+	}
+	if RecordFile != "" {
+		options.HttpClient = &http.Client{Transport: cassette.NewRecorder(options.HttpClient.Transport, RecordFile)}
+	}
+	clientOptions = &options
+	return nil
+}
+
+// The account may be nil for unauthenticated requests.
+func CreateClient(cfg *config.Context) *cloudflare.Client {
+	if clientOptions == nil {
+		return cloudflare.NewClient(cloudflare.DefaultClientOptions(), cfg)
+	}
+	return cloudflare.NewClient(*clientOptions, cfg)
+}
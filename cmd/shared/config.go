@@ -0,0 +1,194 @@
+package shared
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/ViRb3/wgcf/v2/config"
+	"github.com/manifoldco/promptui"
+	"github.com/pkg/errors"
+	"github.com/spf13/viper"
+)
+
+const PassphraseEnv = "WGCF_PASSPHRASE"
+
+// Set by the global --passphrase-file flag.
+var PassphraseFile string
+
+// When set, the config is encrypted with it whenever it's written.
+// Set automatically when an encrypted config is read.
+var ConfigPassphrase string
+
+// The settings written back to the config file: the ones read from it, and the ones set with SetConfigValue.
+// Unlike viper's merged settings, these never include values from the environment, flags or defaults,
+// so e.g. credentials only given as WGCF_ environment variables aren't written to disk.
+var fileSettings = viper.New()
+
+// Sets up viper to use the config file, with WGCF_ environment variables taking precedence.
+func InitConfig(configFile string) {
+	viper.SetDefault(config.DeviceId, "")
+	viper.SetDefault(config.AccessToken, "")
+	viper.SetDefault(config.PrivateKey, "")
+	viper.SetDefault(config.LicenseKey, "")
+	viper.SetConfigFile(configFile)
+	viper.SetEnvPrefix("WGCF")
+	viper.AutomaticEnv()
+	fileSettings = viper.New()
+}
+
+// Sets a value both in viper and in the settings written to the config file.
+func SetConfigValue(key string, value interface{}) {
+	viper.Set(key, value)
+	fileSettings.Set(key, value)
+}
+
+func getConfigType() string {
+	return strings.TrimPrefix(filepath.Ext(viper.ConfigFileUsed()), ".")
+}
+
+// Reads the config file set in viper, transparently decrypting it if needed.
+func ReadConfig() error {
+	data, err := os.ReadFile(viper.ConfigFileUsed())
+	if err != nil {
+		return viper.ReadInConfig()
+	}
+	passphrase := ""
+	if config.IsEncrypted(data) {
+		if passphrase, err = GetPassphrase(false); err != nil {
+			return err
+		}
+		if data, err = config.Decrypt(data, passphrase); err != nil {
+			return err
+		}
+	}
+	viper.SetConfigType(getConfigType())
+	if err := viper.ReadConfig(bytes.NewReader(data)); err != nil {
+		return err
+	}
+	settings := viper.New()
+	settings.SetConfigType(getConfigType())
+	if err := settings.ReadConfig(bytes.NewReader(data)); err != nil {
+		return err
+	}
+	fileSettings = settings
+	if passphrase != "" {
+		ConfigPassphrase = passphrase
+	}
+	return nil
+}
+
+// writes the config to a temporary file next to the original, then renames it over
+// the original, so an interrupted write never leaves a truncated config behind
+func WriteConfigAtomic() error {
+	return writeConfigAtomic(fileSettings)
+}
+
+func writeConfigAtomic(v *viper.Viper) error {
+	return writeConfigAtomicTo(v, viper.ConfigFileUsed())
+}
+
+// Next to the config, e.g. wgcf-account.toml.recovery.
+func GetRecoveryConfigFile() string {
+	return viper.ConfigFileUsed() + ".recovery"
+}
+
+// For when the API already changed a credential, but writing the config failed with err.
+// Writes the config to the recovery file instead, and returns the error to report, which points to it,
+// so the credential is neither lost nor leaked to the logs.
+func SaveRecoveryConfig(credential string, err error) error {
+	recoveryFile := GetRecoveryConfigFile()
+	if recoveryErr := writeConfigAtomicTo(fileSettings, recoveryFile); recoveryErr != nil {
+		return errors.WithMessagef(err, "failed to save new %s, also to %s: %v", credential, recoveryFile, recoveryErr)
+	}
+	return errors.WithMessagef(err, "failed to save new %s, replace %s with %s to restore it",
+		credential, viper.ConfigFileUsed(), recoveryFile)
+}
+
+func writeConfigAtomicTo(v *viper.Viper, path string) error {
+	v.SetConfigType(getConfigType())
+	var buffer bytes.Buffer
+	if err := v.WriteConfigTo(&buffer); err != nil {
+		return err
+	}
+	data := buffer.Bytes()
+	if ConfigPassphrase != "" {
+		var err error
+		if data, err = config.Encrypt(data, ConfigPassphrase); err != nil {
+			return err
+		}
+	}
+
+	return writeFileAtomic(path, data)
+}
+
+// the temporary file is created with mode 0600, which is kept by the rename
+func writeFileAtomic(path string, data []byte) error {
+	tempFile, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+"-*")
+	if err != nil {
+		return err
+	}
+	tempPath := tempFile.Name()
+	if _, err := tempFile.Write(data); err != nil {
+		_ = tempFile.Close()
+		_ = os.Remove(tempPath)
+		return err
+	}
+	if err := tempFile.Close(); err != nil {
+		_ = os.Remove(tempPath)
+		return err
+	}
+	if err := os.Rename(tempPath, path); err != nil {
+		_ = os.Remove(tempPath)
+		return err
+	}
+	return nil
+}
+
+// Reads the passphrase from the passphrase file, the environment, or finally prompts for it.
+// Whether a passphrase was given non-interactively, with --passphrase-file or the environment.
+func HasPassphrase() bool {
+	_, ok := os.LookupEnv(PassphraseEnv)
+	return PassphraseFile != "" || ok
+}
+
+func GetPassphrase(confirm bool) (string, error) {
+	if PassphraseFile != "" {
+		data, err := os.ReadFile(PassphraseFile)
+		if err != nil {
+			return "", err
+		}
+		return checkPassphrase(strings.TrimRight(string(data), "\r\n"))
+	}
+	if passphrase, ok := os.LookupEnv(PassphraseEnv); ok {
+		return checkPassphrase(passphrase)
+	}
+
+	prompt := promptui.Prompt{
+		Label: "Configuration passphrase",
+		Mask:  '*',
+	}
+	passphrase, err := prompt.Run()
+	if err != nil {
+		return "", err
+	}
+	if confirm {
+		prompt.Label = "Confirm passphrase"
+		confirmation, err := prompt.Run()
+		if err != nil {
+			return "", err
+		}
+		if confirmation != passphrase {
+			return "", errors.New("passphrases do not match")
+		}
+	}
+	return checkPassphrase(passphrase)
+}
+
+func checkPassphrase(passphrase string) (string, error) {
+	if passphrase == "" {
+		return "", errors.New("empty passphrase")
+	}
+	return passphrase, nil
+}
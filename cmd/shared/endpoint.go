@@ -0,0 +1,53 @@
+package shared
+
+import (
+	"context"
+	"net/netip"
+
+	"github.com/ViRb3/wgcf/v2/cloudflare"
+	"github.com/ViRb3/wgcf/v2/wireguard"
+	"github.com/pkg/errors"
+)
+
+// Probes the IPv4 and IPv6 endpoint addresses of the device on the ports, with the keys of the device.
+func ScanDeviceEndpoints(ctx context.Context, thisDevice *cloudflare.Device, privateKey string, ports []uint16, options wireguard.ScanOptions) ([]wireguard.ScanResult, error) {
+	if len(thisDevice.Config.Peers) == 0 {
+		return nil, errors.New("device has no peers")
+	}
+	peer := thisDevice.Config.Peers[0]
+	var addresses []netip.Addr
+	// the addresses come with a port, usually 0
+	for _, endpoint := range []string{peer.Endpoint.V4, peer.Endpoint.V6} {
+		if addrPort, err := netip.ParseAddrPort(endpoint); err == nil {
+			addresses = append(addresses, addrPort.Addr())
+		}
+	}
+	if len(addresses) == 0 {
+		return nil, errors.New("device has no endpoint addresses")
+	}
+
+	var err error
+	if options.PrivateKey, err = wireguard.NewKey(privateKey); err != nil {
+		return nil, errors.WithMessage(err, "invalid private key")
+	}
+	if options.PeerPublicKey, err = wireguard.NewKey(peer.PublicKey); err != nil {
+		return nil, errors.WithMessage(err, "invalid peer public key")
+	}
+	// sent by the official clients, some endpoints drop initiations without it
+	if reserved, err := wireguard.DecodeClientId(thisDevice.Config.ClientId); err == nil {
+		options.Reserved = reserved[:]
+	}
+	return wireguard.ScanEndpoints(ctx, wireguard.GetEndpointCandidates(addresses, ports), options), nil
+}
+
+// Returns the endpoint with the least loss and lowest round-trip time, with the default scan options.
+func FindBestEndpoint(ctx context.Context, thisDevice *cloudflare.Device, privateKey string) (netip.AddrPort, error) {
+	results, err := ScanDeviceEndpoints(ctx, thisDevice, privateKey, wireguard.EndpointPorts, wireguard.DefaultScanOptions)
+	if err != nil {
+		return netip.AddrPort{}, err
+	}
+	if len(results) == 0 || results[0].Received == 0 {
+		return netip.AddrPort{}, errors.New("no endpoint responded, UDP may be blocked on this network")
+	}
+	return results[0].Endpoint, nil
+}
@@ -0,0 +1,46 @@
+package shared
+
+import (
+	"log"
+	"os"
+
+	"github.com/ViRb3/wgcf/v2/cloudflare"
+	"github.com/ViRb3/wgcf/v2/util"
+	"github.com/pkg/errors"
+)
+
+// Process exit codes, so scripts can tell the API errors apart.
+const (
+	ExitCodeError              = 1
+	ExitCodeUnauthorized       = 3
+	ExitCodeDeviceLimitReached = 4
+	ExitCodeInvalidLicense     = 5
+	ExitCodeBlockedByWAF       = 6
+	ExitCodeRateLimited        = 7
+)
+
+var exitCodes = map[error]int{
+	cloudflare.ErrUnauthorized:       ExitCodeUnauthorized,
+	cloudflare.ErrDeviceLimitReached: ExitCodeDeviceLimitReached,
+	cloudflare.ErrInvalidLicense:     ExitCodeInvalidLicense,
+	cloudflare.ErrBlockedByWAF:       ExitCodeBlockedByWAF,
+	cloudflare.ErrRateLimited:        ExitCodeRateLimited,
+}
+
+func GetExitCode(err error) int {
+	for kind, code := range exitCodes {
+		if errors.Is(err, kind) {
+			return code
+		}
+	}
+	return ExitCodeError
+}
+
+// Logs the error with its hint, then exits with the exit code of its category.
+func Fatal(err error) {
+	log.Println(util.GetErrorMessage(err))
+	if hint := cloudflare.GetErrorHint(err); hint != "" {
+		log.Println("Hint:", hint)
+	}
+	os.Exit(GetExitCode(err))
+}
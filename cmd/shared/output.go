@@ -0,0 +1,131 @@
+package shared
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+
+	"github.com/ViRb3/wgcf/v2/cloudflare"
+	"github.com/ViRb3/wgcf/v2/wireguard"
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v2"
+)
+
+const (
+	OutputTable = "table"
+	OutputJson  = "json"
+	OutputYaml  = "yaml"
+)
+
+// Set by the global --output flag.
+var OutputFormat = OutputTable
+
+// Where the output is printed, replaced in tests.
+var Stdout io.Writer = os.Stdout
+
+func IsValidOutputFormat(format string) bool {
+	return format == OutputTable || format == OutputJson || format == OutputYaml
+}
+
+// A command result which can be rendered in any of the output formats.
+// Field names in the json tags are the stable, machine-readable schema.
+type Output interface {
+	PrintTable()
+}
+
+// Prints the output to stdout in the selected format. Tables are kept on stderr
+// next to the rest of the logs, as they are meant for humans.
+func PrintOutput(output Output) error {
+	switch OutputFormat {
+	case OutputJson:
+		data, err := json.MarshalIndent(output, "", "  ")
+		if err != nil {
+			return err
+		}
+		_, _ = fmt.Fprintln(Stdout, string(data))
+	case OutputYaml:
+		data, err := toYaml(output)
+		if err != nil {
+			return err
+		}
+		_, _ = fmt.Fprint(Stdout, string(data))
+	case OutputTable:
+		output.PrintTable()
+	default:
+		return errors.New("unsupported output format: " + OutputFormat)
+	}
+	return nil
+}
+
+// goes through json so the yaml keys and their order match the json schema
+func toYaml(output Output) ([]byte, error) {
+	data, err := json.Marshal(output)
+	if err != nil {
+		return nil, err
+	}
+	var mapSlice yaml.MapSlice
+	if err := yaml.Unmarshal(data, &mapSlice); err != nil {
+		return nil, err
+	}
+	return yaml.Marshal(mapSlice)
+}
+
+type DeviceData struct {
+	DeviceName   string `json:"device_name"`
+	DeviceModel  string `json:"device_model"`
+	DeviceActive bool   `json:"device_active"`
+	AccountType  string `json:"account_type"`
+	Role         string `json:"role"`
+	// in bytes
+	PremiumData uint64 `json:"premium_data"`
+	// in bytes
+	Quota uint64 `json:"quota"`
+	// base64 of the reserved bytes, which some WireGuard implementations need to be set
+	ClientId string `json:"client_id"`
+	// the decoded client id, empty if it's invalid
+	Reserved []int `json:"reserved"`
+	// only set when a profile was generated
+	ProfilePath string `json:"profile_path,omitempty"`
+}
+
+func NewDeviceData(thisDevice *cloudflare.Device, boundDevice *cloudflare.BoundDevice) *DeviceData {
+	deviceName := ""
+	if boundDevice.Name != nil {
+		deviceName = *boundDevice.Name
+	}
+	data := &DeviceData{
+		DeviceName:   deviceName,
+		DeviceModel:  thisDevice.Model,
+		DeviceActive: boundDevice.Active,
+		AccountType:  thisDevice.Account.AccountType,
+		Role:         thisDevice.Account.Role,
+		PremiumData:  uint64(thisDevice.Account.PremiumData),
+		Quota:        uint64(thisDevice.Account.Quota),
+		ClientId:     thisDevice.Config.ClientId,
+		Reserved:     []int{},
+	}
+	if reserved, err := wireguard.DecodeClientId(thisDevice.Config.ClientId); err == nil {
+		data.Reserved = reserved.Ints()
+	}
+	return data
+}
+
+func (d *DeviceData) PrintTable() {
+	log.Println("=======================================")
+	log.Printf("%-13s : %s\n", "Device name", d.DeviceName)
+	log.Printf("%-13s : %s\n", "Device model", d.DeviceModel)
+	log.Printf("%-13s : %t\n", "Device active", d.DeviceActive)
+	log.Printf("%-13s : %s\n", "Account type", d.AccountType)
+	log.Printf("%-13s : %s\n", "Role", d.Role)
+	log.Printf("%-13s : %s\n", "Premium data", F32ToHumanReadable(float32(d.PremiumData)))
+	log.Printf("%-13s : %s\n", "Quota", F32ToHumanReadable(float32(d.Quota)))
+	if reserved, err := wireguard.DecodeClientId(d.ClientId); err == nil {
+		log.Printf("%-13s : %s (client id %s)\n", "Reserved", reserved, d.ClientId)
+	}
+	if d.ProfilePath != "" {
+		log.Printf("%-13s : %s\n", "Profile", d.ProfilePath)
+	}
+	log.Println("=======================================")
+}
@@ -0,0 +1,98 @@
+package shared
+
+import (
+	"encoding/json"
+	"os"
+
+	"github.com/ViRb3/wgcf/v2/config"
+	"github.com/spf13/viper"
+)
+
+// The credentials of a device which was registered, but not yet activated and saved to the config.
+type pendingRegistration struct {
+	DeviceId    string `json:"device_id"`
+	AccessToken string `json:"access_token"`
+	PrivateKey  string `json:"private_key"`
+	LicenseKey  string `json:"license_key"`
+}
+
+// Next to the config, one per account, e.g. wgcf-account.toml.pending.
+func GetPendingRegistrationFile() string {
+	if name := GetActiveAccountName(); name != "" {
+		return viper.ConfigFileUsed() + "." + name + ".pending"
+	}
+	return viper.ConfigFileUsed() + ".pending"
+}
+
+// Stages the credentials of a new device, so the registration can be resumed if it's interrupted.
+// Only the private key is set if the device isn't registered yet.
+func SavePendingRegistration(cfg *config.Context) error {
+	data, err := json.Marshal(pendingRegistration{
+		DeviceId:    cfg.DeviceId,
+		AccessToken: cfg.AccessToken,
+		PrivateKey:  cfg.PrivateKey,
+		LicenseKey:  cfg.LicenseKey,
+	})
+	if err != nil {
+		return err
+	}
+	passphrase, err := getPendingPassphrase()
+	if err != nil {
+		return err
+	}
+	if passphrase != "" {
+		if data, err = config.Encrypt(data, passphrase); err != nil {
+			return err
+		}
+	}
+	return writeFileAtomic(GetPendingRegistrationFile(), data)
+}
+
+// Encrypted like the config, or with the passphrase given non-interactively, even if the config isn't encrypted.
+func getPendingPassphrase() (string, error) {
+	if ConfigPassphrase != "" {
+		return ConfigPassphrase, nil
+	}
+	if HasPassphrase() {
+		return GetPassphrase(false)
+	}
+	return "", nil
+}
+
+// Returns nil if there is no pending registration.
+func LoadPendingRegistration() (*config.Context, error) {
+	data, err := os.ReadFile(GetPendingRegistrationFile())
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	if config.IsEncrypted(data) {
+		passphrase := ConfigPassphrase
+		if passphrase == "" {
+			if passphrase, err = GetPassphrase(false); err != nil {
+				return nil, err
+			}
+		}
+		if data, err = config.Decrypt(data, passphrase); err != nil {
+			return nil, err
+		}
+	}
+	var pending pendingRegistration
+	if err := json.Unmarshal(data, &pending); err != nil {
+		return nil, err
+	}
+	return &config.Context{
+		DeviceId:    pending.DeviceId,
+		AccessToken: pending.AccessToken,
+		PrivateKey:  pending.PrivateKey,
+		LicenseKey:  pending.LicenseKey,
+	}, nil
+}
+
+func RemovePendingRegistration() error {
+	if err := os.Remove(GetPendingRegistrationFile()); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
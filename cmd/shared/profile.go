@@ -0,0 +1,139 @@
+package shared
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/ViRb3/wgcf/v2/cloudflare"
+	"github.com/ViRb3/wgcf/v2/config"
+	"github.com/ViRb3/wgcf/v2/wireguard"
+	"github.com/pkg/errors"
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+)
+
+// Set as the only DNS server, disables DNS in the profile.
+const NoDNS = "none"
+
+func profileFlagName(key string) string {
+	return strings.ReplaceAll(strings.TrimPrefix(key, "profile_"), "_", "-")
+}
+
+// Adds a flag for every profile setting, overriding the config.
+func AddProfileFlags(flags *pflag.FlagSet) {
+	flags.Int(profileFlagName(config.ProfileMTU), 0, "Interface MTU (defaults to 1280)")
+	flags.StringSlice(profileFlagName(config.ProfileDNS), nil, "DNS servers, or "+NoDNS+" (defaults to Cloudflare's)")
+	flags.StringSlice(profileFlagName(config.ProfileAllowedIPs), nil, "Networks (CIDR) to route through the tunnel, before exclusions (defaults to all)")
+	flags.Int(profileFlagName(config.ProfileKeepalive), 0, "Persistent keepalive interval in seconds (defaults to off)")
+	flags.String(profileFlagName(config.ProfileTable), "", "wg-quick routing table, e.g. off, auto or a table number")
+	flags.String(profileFlagName(config.ProfileFwMark), "", "Firewall mark of outgoing packets, e.g. 51820 or 0xca6c")
+	flags.StringArray(profileFlagName(config.ProfilePreUp), nil, "wg-quick command to run before bringing the interface up, repeatable")
+	flags.StringArray(profileFlagName(config.ProfilePostUp), nil, "wg-quick command to run after bringing the interface up, repeatable")
+	flags.StringArray(profileFlagName(config.ProfilePreDown), nil, "wg-quick command to run before bringing the interface down, repeatable")
+	flags.StringArray(profileFlagName(config.ProfilePostDown), nil, "wg-quick command to run after bringing the interface down, repeatable")
+	flags.String(profileFlagName(config.ProfileIPMode), "", "Address families to use, one of: "+
+		strings.Join([]string{wireguard.IPModeDual, wireguard.IPModeV4, wireguard.IPModeV6}, ", ")+" (defaults to "+wireguard.IPModeDual+")")
+}
+
+// Reads the profile settings from the flags if set, falling back to the config, and validates them.
+// The flags may be nil to only use the config.
+func GetProfileSettings(flags *pflag.FlagSet) (*wireguard.ProfileData, error) {
+	changed := func(key string) *pflag.Flag {
+		if flags == nil {
+			return nil
+		}
+		if flag := flags.Lookup(profileFlagName(key)); flag != nil && flag.Changed {
+			return flag
+		}
+		return nil
+	}
+	getInt := func(key string) int {
+		if flag := changed(key); flag != nil {
+			value, _ := flags.GetInt(flag.Name)
+			return value
+		}
+		return viper.GetInt(key)
+	}
+	getString := func(key string) string {
+		if flag := changed(key); flag != nil {
+			return flag.Value.String()
+		}
+		return viper.GetString(key)
+	}
+	getStrings := func(key string) []string {
+		if flag := changed(key); flag != nil {
+			return flag.Value.(pflag.SliceValue).GetSlice()
+		}
+		return viper.GetStringSlice(key)
+	}
+
+	data := &wireguard.ProfileData{
+		MTU:                 getInt(config.ProfileMTU),
+		AllowedIPs:          getStrings(config.ProfileAllowedIPs),
+		PersistentKeepalive: getInt(config.ProfileKeepalive),
+		Table:               getString(config.ProfileTable),
+		FwMark:              getString(config.ProfileFwMark),
+		PreUp:               getStrings(config.ProfilePreUp),
+		PostUp:              getStrings(config.ProfilePostUp),
+		PreDown:             getStrings(config.ProfilePreDown),
+		PostDown:            getStrings(config.ProfilePostDown),
+		IPMode:              getString(config.ProfileIPMode),
+	}
+	if dns := getStrings(config.ProfileDNS); len(dns) == 1 && dns[0] == NoDNS {
+		data.DNS = []string{}
+	} else if len(dns) > 0 {
+		data.DNS = dns
+	}
+	if err := data.Validate(); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// Fills in the device specific fields of the profile.
+func SetProfileDevice(data *wireguard.ProfileData, thisDevice *cloudflare.Device, privateKey string) {
+	data.PrivateKey = privateKey
+	data.Address1 = thisDevice.Config.Interface.Addresses.V4
+	data.Address2 = thisDevice.Config.Interface.Addresses.V6
+	data.PublicKey = thisDevice.Config.Peers[0].PublicKey
+	data.Endpoint = thisDevice.Config.Peers[0].Endpoint.Host
+	data.EndpointV4 = thisDevice.Config.Peers[0].Endpoint.V4
+	data.EndpointV6 = thisDevice.Config.Peers[0].Endpoint.V6
+	data.DeviceId = thisDevice.Id
+	data.AccountType = thisDevice.Account.AccountType
+	data.ClientId = thisDevice.Config.ClientId
+}
+
+// Adds the --format and --template flags, which select how the profile is rendered.
+func AddFormatFlags(flags *pflag.FlagSet) {
+	flags.StringP("format", "f", wireguard.DefaultFormat, "Profile format, one of: "+strings.Join(wireguard.GetFormatNames(), ", "))
+	flags.String("template", "", "Go text/template file to render the profile with, instead of a format")
+}
+
+// Returns the formatter selected by --format or --template, and the profile file,
+// whose extension follows the format unless --profile was given.
+func GetFormatter(flags *pflag.FlagSet, profileFile string) (wireguard.Formatter, string, error) {
+	format, _ := flags.GetString("format")
+	templateFile, _ := flags.GetString("template")
+	if templateFile != "" {
+		if flags.Changed("format") {
+			return nil, "", errors.New("--format and --template can't be used together")
+		}
+		text, err := os.ReadFile(templateFile)
+		if err != nil {
+			return nil, "", err
+		}
+		// the template decides the file type, so the profile file is used as is
+		formatter, err := wireguard.NewTemplateFormatter(string(text))
+		return formatter, profileFile, err
+	}
+	formatter, ok := wireguard.Formatters[format]
+	if !ok {
+		return nil, "", errors.Errorf("unknown profile format %s, must be one of: %s", format, strings.Join(wireguard.GetFormatNames(), ", "))
+	}
+	if !flags.Changed("profile") {
+		profileFile = strings.TrimSuffix(profileFile, filepath.Ext(profileFile)) + wireguard.GetFormatExtension(formatter)
+	}
+	return formatter, profileFile, nil
+}
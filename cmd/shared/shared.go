@@ -1,17 +1,17 @@
 package shared
 
 import (
+	"context"
 	"fmt"
-	"log"
 	"math"
 	"strings"
 
 	"github.com/ViRb3/wgcf/v2/cloudflare"
 	"github.com/ViRb3/wgcf/v2/config"
 	"github.com/ViRb3/wgcf/v2/util"
+	"github.com/ViRb3/wgcf/v2/wireguard"
 
 	"github.com/pkg/errors"
-	"github.com/spf13/viper"
 )
 
 func FormatMessage(shortMessage string, longMessage string) string {
@@ -31,16 +31,17 @@ func FormatMessage(shortMessage string, longMessage string) string {
 }
 
 func IsConfigValidAccount() bool {
-	return viper.GetString(config.DeviceId) != "" &&
-		viper.GetString(config.AccessToken) != "" &&
-		viper.GetString(config.PrivateKey) != ""
+	return GetAccountValue(config.DeviceId) != "" &&
+		GetAccountValue(config.AccessToken) != "" &&
+		GetAccountValue(config.PrivateKey) != ""
 }
 
 func CreateContext() *config.Context {
 	ctx := config.Context{
-		DeviceId:    viper.GetString(config.DeviceId),
-		AccessToken: viper.GetString(config.AccessToken),
-		LicenseKey:  viper.GetString(config.LicenseKey),
+		DeviceId:    GetAccountValue(config.DeviceId),
+		AccessToken: GetAccountValue(config.AccessToken),
+		PrivateKey:  GetAccountValue(config.PrivateKey),
+		LicenseKey:  GetAccountValue(config.LicenseKey),
 	}
 	return &ctx
 }
@@ -55,24 +56,12 @@ func F32ToHumanReadable(number float32) string {
 	return fmt.Sprintf("%.2f B", number)
 }
 
-func PrintDeviceData(thisDevice *cloudflare.Device, boundDevice *cloudflare.BoundDevice) {
-	log.Println("=======================================")
-	log.Printf("%-13s : %s\n", "Device name", *boundDevice.Name)
-	log.Printf("%-13s : %s\n", "Device model", thisDevice.Model)
-	log.Printf("%-13s : %t\n", "Device active", boundDevice.Active)
-	log.Printf("%-13s : %s\n", "Account type", thisDevice.Account.AccountType)
-	log.Printf("%-13s : %s\n", "Role", thisDevice.Account.Role)
-	log.Printf("%-13s : %s\n", "Premium data", F32ToHumanReadable(thisDevice.Account.PremiumData))
-	log.Printf("%-13s : %s\n", "Quota", F32ToHumanReadable(thisDevice.Account.Quota))
-	log.Println("=======================================")
-}
-
 // changing the bound account (e.g. changing license key) will reset the device name
-func SetDeviceName(ctx *config.Context, deviceName string) (*cloudflare.BoundDevice, error) {
+func SetDeviceName(ctx context.Context, client *cloudflare.Client, deviceName string) (*cloudflare.BoundDevice, error) {
 	if deviceName == "" {
 		deviceName += util.RandomHexString(3)
 	}
-	device, err := cloudflare.UpdateSourceBoundDeviceName(ctx, deviceName)
+	device, err := client.UpdateSourceBoundDeviceName(ctx, deviceName)
 	if err != nil {
 		return nil, err
 	}
@@ -81,3 +70,15 @@ func SetDeviceName(ctx *config.Context, deviceName string) (*cloudflare.BoundDev
 	}
 	return device, nil
 }
+
+// Saves the profile in the given format, and returns the paths of the saved files.
+func SaveProfile(data *wireguard.ProfileData, formatter wireguard.Formatter, profileFile string) ([]string, error) {
+	profile, err := wireguard.NewFormattedProfile(data, formatter)
+	if err != nil {
+		return nil, err
+	}
+	if err := profile.Save(profileFile); err != nil {
+		return nil, err
+	}
+	return profile.GetPaths(profileFile), nil
+}
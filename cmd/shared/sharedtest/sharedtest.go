@@ -0,0 +1,49 @@
+// Package sharedtest prepares the commands to run against the mock API in tests.
+package sharedtest
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/ViRb3/wgcf/v2/cloudflare/cftest"
+	"github.com/ViRb3/wgcf/v2/cmd/shared"
+	"github.com/ViRb3/wgcf/v2/config"
+	"github.com/ViRb3/wgcf/v2/openapi"
+	"github.com/ViRb3/wgcf/v2/wireguard"
+	"github.com/spf13/viper"
+)
+
+// Resets the config to a new file in a temporary directory, which doesn't exist yet,
+// and points the API client at the server. Returns the path of the config file.
+func Setup(t *testing.T, server *cftest.Server) string {
+	t.Helper()
+	configFile := filepath.Join(t.TempDir(), "wgcf-account.toml")
+	viper.Reset()
+	shared.InitConfig(configFile)
+	shared.ConfigPassphrase = ""
+	shared.SettingFlags[config.ApiUrl] = server.URL
+	t.Cleanup(func() { delete(shared.SettingFlags, config.ApiUrl) })
+	if err := shared.InitClientOptions(); err != nil {
+		t.Fatal(err)
+	}
+	return configFile
+}
+
+// Registers a device with the server and sets it as the active account, without writing the config.
+func Register(t *testing.T) openapi.Register200Response {
+	t.Helper()
+	privateKey, err := wireguard.NewPrivateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	device, err := shared.CreateClient(nil).Register(context.Background(), privateKey.Public(), "PC")
+	if err != nil {
+		t.Fatal(err)
+	}
+	shared.SetAccountValue(config.DeviceId, device.Id)
+	shared.SetAccountValue(config.AccessToken, device.Token)
+	shared.SetAccountValue(config.PrivateKey, privateKey.String())
+	shared.SetAccountValue(config.LicenseKey, device.Account.License)
+	return device
+}
@@ -0,0 +1,113 @@
+package shared
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/ViRb3/wgcf/v2/cloudflare"
+	"github.com/ViRb3/wgcf/v2/wireguard"
+	"github.com/pkg/errors"
+	"github.com/spf13/pflag"
+)
+
+// Keeps NAT mappings open, and makes the first handshake happen right away.
+const DefaultKeepalive = 25
+
+// WireGuard rekeys every 2 minutes, so an older handshake means the peer isn't reachable.
+const StaleHandshakeAge = 3 * time.Minute
+
+// Brings up the tunnel of the current account on a TUN interface, or on a userspace network stack
+// if the interface name is empty, which is only reachable through the returned tunnel.
+func OpenTunnel(ctx context.Context, flags *pflag.FlagSet, offline bool, interfaceName string) (*wireguard.Tunnel, error) {
+	if !IsConfigValidAccount() {
+		return nil, errors.New("no account detected")
+	}
+	profileData, err := GetProfileSettings(flags)
+	if err != nil {
+		return nil, err
+	}
+	if profileData.PersistentKeepalive == 0 {
+		profileData.PersistentKeepalive = DefaultKeepalive
+	}
+	thisDevice, err := GetDeviceConfig(ctx, offline)
+	if err != nil {
+		return nil, err
+	}
+	SetProfileDevice(profileData, thisDevice, CreateContext().PrivateKey)
+
+	if interfaceName == "" {
+		return wireguard.NewNetstackTunnel(profileData)
+	}
+	tunnel, err := wireguard.NewTUNTunnel(profileData, interfaceName)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "failed to create TUN interface %s, which requires root or CAP_NET_ADMIN; "+
+			"to use Warp without privileges, run the proxy command instead", interfaceName)
+	}
+	return tunnel, nil
+}
+
+// Returns the device with its configuration, either from the API, caching it, or from the cache.
+func GetDeviceConfig(ctx context.Context, offline bool) (*cloudflare.Device, error) {
+	if offline {
+		thisDevice, cachedAt, err := GetCachedDevice()
+		if err != nil {
+			return nil, err
+		}
+		if age := time.Since(cachedAt); age > DeviceCacheMaxAge {
+			log.Printf("Warning: the cached device configuration is %d days old and may be outdated, "+
+				"run status once while online to refresh it\n", int(age.Hours()/24))
+		}
+		return thisDevice, nil
+	}
+	thisDevice, err := CreateClient(CreateContext()).GetSourceDevice(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if CacheDeviceConfig(thisDevice) {
+		if err := WriteConfigAtomic(); err != nil {
+			log.Println("Warning: failed to cache device configuration:", err)
+		}
+	}
+	return thisDevice, nil
+}
+
+// Logs whether the tunnel is connected until the context is done, which isn't an error.
+func MonitorHandshake(ctx context.Context, tunnel *wireguard.Tunnel, timeout time.Duration) error {
+	waitCtx, cancel := context.WithTimeout(ctx, timeout)
+	err := tunnel.WaitHandshake(waitCtx)
+	cancel()
+	if ctx.Err() != nil {
+		return nil
+	}
+	connected := err == nil
+	if connected {
+		log.Println("Handshake completed, connected to Cloudflare Warp")
+	} else if errors.Is(err, context.DeadlineExceeded) {
+		log.Printf("Warning: no handshake after %s, the endpoint may be blocked; still retrying\n", timeout)
+	} else {
+		return err
+	}
+
+	ticker := time.NewTicker(10 * time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			log.Println("Shutting down the tunnel")
+			return nil
+		case <-ticker.C:
+		}
+		lastHandshake, err := tunnel.LastHandshake()
+		if err != nil {
+			return err
+		}
+		fresh := !lastHandshake.IsZero() && time.Since(lastHandshake) < StaleHandshakeAge
+		if fresh && !connected {
+			log.Println("Handshake completed, connected to Cloudflare Warp")
+		} else if !fresh && connected {
+			log.Println("Warning: no handshake since", lastHandshake.Format(time.RFC3339), "; still retrying")
+		}
+		connected = fresh
+	}
+}
@@ -1,11 +1,10 @@
 package status
 
 import (
+	"context"
 	"log"
 
-	"github.com/ViRb3/wgcf/v2/cloudflare"
 	. "github.com/ViRb3/wgcf/v2/cmd/shared"
-	"github.com/ViRb3/wgcf/v2/util"
 	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
 )
@@ -17,8 +16,8 @@ var Cmd = &cobra.Command{
 	Short: shortMsg,
 	Long:  FormatMessage(shortMsg, ``),
 	Run: func(cmd *cobra.Command, args []string) {
-		if err := status(); err != nil {
-			log.Fatal(util.GetErrorMessage(err))
+		if err := status(cmd.Context()); err != nil {
+			Fatal(err)
 		}
 	},
 }
@@ -26,21 +25,27 @@ var Cmd = &cobra.Command{
 func init() {
 }
 
-func status() error {
+func status(ctx context.Context) error {
 	if !IsConfigValidAccount() {
 		return errors.New("no valid account detected")
 	}
 
-	ctx := CreateContext()
-	thisDevice, err := cloudflare.GetSourceDevice(ctx)
+	cfg := CreateContext()
+	client := CreateClient(cfg)
+	thisDevice, err := client.GetSourceDevice(ctx)
 	if err != nil {
 		return err
 	}
-	boundDevice, err := cloudflare.GetSourceBoundDevice(ctx)
+	boundDevice, err := client.GetSourceBoundDevice(ctx)
 	if err != nil {
 		return err
 	}
 
-	PrintDeviceData(thisDevice, boundDevice)
-	return nil
+	if CacheDeviceConfig(thisDevice) {
+		if err := WriteConfigAtomic(); err != nil {
+			log.Println("Warning: failed to cache device configuration:", err)
+		}
+	}
+
+	return PrintOutput(NewDeviceData(thisDevice, boundDevice))
 }
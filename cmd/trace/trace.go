@@ -1,6 +1,8 @@
 package trace
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"log"
@@ -8,7 +10,6 @@ import (
 	"strings"
 
 	. "github.com/ViRb3/wgcf/v2/cmd/shared"
-	"github.com/ViRb3/wgcf/v2/util"
 	"github.com/spf13/cobra"
 )
 
@@ -20,8 +21,8 @@ var Cmd = &cobra.Command{
 	Long: FormatMessage(shortMsg, `
 Useful for verifying if Warp and Warp+ are working.`),
 	Run: func(cmd *cobra.Command, args []string) {
-		if err := trace(); err != nil {
-			log.Fatal(util.GetErrorMessage(err))
+		if err := trace(cmd.Context()); err != nil {
+			Fatal(err)
 		}
 	},
 }
@@ -29,16 +30,44 @@ Useful for verifying if Warp and Warp+ are working.`),
 func init() {
 }
 
-func trace() error {
-	response, err := http.Get("https://cloudflare.com/cdn-cgi/trace")
+func trace(ctx context.Context) error {
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, "https://cloudflare.com/cdn-cgi/trace", nil)
 	if err != nil {
 		return err
 	}
+	response, err := http.DefaultClient.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
 	bodyBytes, err := ioutil.ReadAll(response.Body)
 	if err != nil {
 		return err
 	}
+	return PrintOutput(newTraceData(string(bodyBytes)))
+}
+
+type traceData struct {
+	raw    string
+	fields map[string]string
+}
+
+func newTraceData(body string) *traceData {
+	data := traceData{raw: strings.TrimSpace(body), fields: map[string]string{}}
+	for _, line := range strings.Split(data.raw, "\n") {
+		if key, value, found := strings.Cut(line, "="); found {
+			data.fields[key] = value
+		}
+	}
+	return &data
+}
+
+// the schema is the trace key/value pairs as returned by Cloudflare, e.g. ip, colo, warp
+func (t *traceData) MarshalJSON() ([]byte, error) {
+	return json.Marshal(t.fields)
+}
+
+func (t *traceData) PrintTable() {
 	log.Println("Trace result:")
-	fmt.Println(strings.TrimSpace(string(bodyBytes)))
-	return nil
+	fmt.Println(t.raw)
 }
@@ -0,0 +1,57 @@
+package trace
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ViRb3/wgcf/v2/cmd/shared"
+)
+
+var updateGolden = flag.Bool("update", false, "update the golden files")
+
+const traceBody = `fl=12f1
+h=cloudflare.com
+ip=203.0.113.1
+ts=1700000000.123
+visit_scheme=https
+uag=Go-http-client/2.0
+colo=AMS
+sliver=none
+http=http/2
+loc=NL
+tls=TLSv1.3
+sni=plaintext
+warp=plus
+gateway=off
+rbi=off
+kex=X25519
+`
+
+func TestTraceJsonGolden(t *testing.T) {
+	var stdout bytes.Buffer
+	shared.Stdout = &stdout
+	defer func() { shared.Stdout = os.Stdout }()
+	shared.OutputFormat = shared.OutputJson
+	defer func() { shared.OutputFormat = shared.OutputTable }()
+
+	if err := shared.PrintOutput(newTraceData(traceBody)); err != nil {
+		t.Fatal(err)
+	}
+
+	goldenFile := filepath.Join("testdata", "trace.json.golden")
+	if *updateGolden {
+		if err := os.WriteFile(goldenFile, stdout.Bytes(), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	expected, err := os.ReadFile(goldenFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(expected, stdout.Bytes()) {
+		t.Fatalf("%s does not match, got:\n%s", goldenFile, stdout.String())
+	}
+}
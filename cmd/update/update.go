@@ -1,12 +1,12 @@
 package update
 
 import (
+	"context"
 	"log"
 
 	"github.com/ViRb3/wgcf/v2/cloudflare"
 	. "github.com/ViRb3/wgcf/v2/cmd/shared"
 	"github.com/ViRb3/wgcf/v2/config"
-	"github.com/ViRb3/wgcf/v2/util"
 	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
 )
@@ -22,8 +22,8 @@ var Cmd = &cobra.Command{
 If a new/different license key is provided, the current device will be bound to the new key and its parent account. 
 Please note that there is a maximum limit of 5 active devices linked to the same account at a given time.`),
 	Run: func(cmd *cobra.Command, args []string) {
-		if err := updateAccount(); err != nil {
-			log.Fatal(util.GetErrorMessage(err))
+		if err := updateAccount(cmd.Context()); err != nil {
+			Fatal(err)
 		}
 	},
 }
@@ -32,33 +32,34 @@ func init() {
 	Cmd.PersistentFlags().StringVarP(&deviceName, "name", "n", "", "Device name displayed under the 1.1.1.1 app")
 }
 
-func updateAccount() error {
+func updateAccount(ctx context.Context) error {
 	if !IsConfigValidAccount() {
 		return errors.New("no account detected")
 	}
 
-	ctx := CreateContext()
-	thisDevice, err := cloudflare.GetSourceDevice(ctx)
+	cfg := CreateContext()
+	client := CreateClient(cfg)
+	thisDevice, err := client.GetSourceDevice(ctx)
 	if err != nil {
 		return err
 	}
-	_, thisDevice, err = ensureLicenseKeyUpToDate(ctx, thisDevice)
+	_, thisDevice, err = ensureLicenseKeyUpToDate(ctx, client, cfg, thisDevice)
 	if err != nil {
 		return err
 	}
 
-	boundDevice, err := cloudflare.GetSourceBoundDevice(ctx)
+	boundDevice, err := client.GetSourceBoundDevice(ctx)
 	if err != nil {
 		return err
 	}
 	if boundDevice.Name == nil || (deviceName != "" && deviceName != *boundDevice.Name) {
 		log.Println("Setting device name")
-		if _, err := SetDeviceName(ctx, deviceName); err != nil {
+		if _, err := SetDeviceName(ctx, client, deviceName); err != nil {
 			return err
 		}
 	}
 
-	boundDevice, err = cloudflare.UpdateSourceBoundDeviceActive(ctx, true)
+	boundDevice, err = client.UpdateSourceBoundDeviceActive(ctx, true)
 	if err != nil {
 		return err
 	}
@@ -66,35 +67,43 @@ func updateAccount() error {
 		return errors.New("failed activating device")
 	}
 
-	PrintDeviceData(thisDevice, boundDevice)
+	if CacheDeviceConfig(thisDevice) {
+		if err := WriteConfigAtomic(); err != nil {
+			log.Println("Warning: failed to cache device configuration:", err)
+		}
+	}
+
+	if err := PrintOutput(NewDeviceData(thisDevice, boundDevice)); err != nil {
+		return err
+	}
 	log.Println("Successfully updated Cloudflare Warp account")
 	return nil
 }
 
-func ensureLicenseKeyUpToDate(ctx *config.Context, thisDevice *cloudflare.Device) (*cloudflare.Account, *cloudflare.Device, error) {
-	if thisDevice.Account.License != ctx.LicenseKey {
+func ensureLicenseKeyUpToDate(ctx context.Context, client *cloudflare.Client, cfg *config.Context, thisDevice *cloudflare.Device) (*cloudflare.Account, *cloudflare.Device, error) {
+	if thisDevice.Account.License != cfg.LicenseKey {
 		log.Println("Updated license key detected, re-binding device to new account")
-		return updateLicenseKey(ctx)
+		return updateLicenseKey(ctx, client, cfg)
 	}
 	return nil, thisDevice, nil
 }
 
-func updateLicenseKey(ctx *config.Context) (*cloudflare.Account, *cloudflare.Device, error) {
+func updateLicenseKey(ctx context.Context, client *cloudflare.Client, cfg *config.Context) (*cloudflare.Account, *cloudflare.Device, error) {
 
-	if _, err := cloudflare.UpdateLicenseKey(ctx); err != nil {
+	if _, err := client.UpdateLicenseKey(ctx); err != nil {
 		return nil, nil, err
 	}
 
-	account, err := cloudflare.GetAccount(ctx)
+	account, err := client.GetAccount(ctx)
 	if err != nil {
 		return nil, nil, err
 	}
-	thisDevice, err := cloudflare.GetSourceDevice(ctx)
+	thisDevice, err := client.GetSourceDevice(ctx)
 	if err != nil {
 		return nil, nil, err
 	}
 
-	if account.License != ctx.LicenseKey {
+	if account.License != cfg.LicenseKey {
 		return nil, nil, errors.New("failed to update license key")
 	}
 
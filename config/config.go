@@ -5,8 +5,43 @@ const (
 	AccessToken = "access_token"
 	PrivateKey  = "private_key"
 	LicenseKey  = "license_key"
+
+	// last known device configuration, so profiles can be generated offline
+	AddressV4     = "address_v4"
+	AddressV6     = "address_v6"
+	PeerPublicKey = "peer_public_key"
+	EndpointHost  = "endpoint_host"
+	EndpointV4    = "endpoint_v4"
+	EndpointV6    = "endpoint_v6"
+	ClientId      = "client_id"
+	CachedAt      = "cached_at"
+
+	Accounts       = "accounts"
+	DefaultAccount = "default_account"
+
+	// profile settings, which can be overridden by the generate flag of the same name without the prefix
+	ProfileMTU        = "profile_mtu"
+	ProfileDNS        = "profile_dns"
+	ProfileAllowedIPs = "profile_allowed_ips"
+	ProfileKeepalive  = "profile_keepalive"
+	ProfileTable      = "profile_table"
+	ProfileFwMark     = "profile_fwmark"
+	ProfilePreUp      = "profile_pre_up"
+	ProfilePostUp     = "profile_post_up"
+	ProfilePreDown    = "profile_pre_down"
+	ProfilePostDown   = "profile_post_down"
+	ProfileIPMode     = "profile_ip_mode"
+
+	ApiUrl         = "api_url"
+	ApiVersion     = "api_version"
+	ClientIdentity = "client_identity"
+	UserAgent      = "user_agent"
+	ClientVersion  = "client_version"
+	DeviceType     = "device_type"
 )
 
+var CacheKeys = []string{AddressV4, AddressV6, PeerPublicKey, EndpointHost, EndpointV4, EndpointV6, ClientId, CachedAt}
+
 type Context struct {
 	DeviceId    string
 	AccessToken string
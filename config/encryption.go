@@ -0,0 +1,81 @@
+package config
+
+import (
+	"bytes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+
+	"github.com/pkg/errors"
+	"golang.org/x/crypto/chacha20poly1305"
+	"golang.org/x/crypto/scrypt"
+)
+
+// Encrypted files consist of this header line, followed by the base64 of salt || nonce || ciphertext.
+// The header is also authenticated as additional data.
+var encryptedHeader = []byte("# wgcf encrypted configuration v1\n")
+
+const (
+	saltLength = 16
+	scryptN    = 1 << 15
+	scryptR    = 8
+	scryptP    = 1
+)
+
+func IsEncrypted(data []byte) bool {
+	return bytes.HasPrefix(data, encryptedHeader)
+}
+
+func Encrypt(plaintext []byte, passphrase string) ([]byte, error) {
+	salt := make([]byte, saltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+	aead, err := newCipher(passphrase, salt)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+
+	payload := append(salt, nonce...)
+	payload = aead.Seal(payload, nonce, plaintext, encryptedHeader)
+
+	result := append([]byte{}, encryptedHeader...)
+	result = append(result, base64.StdEncoding.EncodeToString(payload)...)
+	return append(result, '\n'), nil
+}
+
+func Decrypt(data []byte, passphrase string) ([]byte, error) {
+	if !IsEncrypted(data) {
+		return nil, errors.New("configuration is not encrypted")
+	}
+	payload, err := base64.StdEncoding.DecodeString(string(bytes.TrimSpace(data[len(encryptedHeader):])))
+	if err != nil {
+		return nil, errors.WithMessage(err, "decode encrypted configuration")
+	}
+	if len(payload) < saltLength+chacha20poly1305.NonceSizeX {
+		return nil, errors.New("encrypted configuration is truncated")
+	}
+	salt := payload[:saltLength]
+	nonce := payload[saltLength : saltLength+chacha20poly1305.NonceSizeX]
+	aead, err := newCipher(passphrase, salt)
+	if err != nil {
+		return nil, err
+	}
+	plaintext, err := aead.Open(nil, nonce, payload[saltLength+len(nonce):], encryptedHeader)
+	if err != nil {
+		return nil, errors.New("failed to decrypt configuration, wrong passphrase?")
+	}
+	return plaintext, nil
+}
+
+func newCipher(passphrase string, salt []byte) (cipher.AEAD, error) {
+	key, err := scrypt.Key([]byte(passphrase), salt, scryptN, scryptR, scryptP, chacha20poly1305.KeySize)
+	if err != nil {
+		return nil, err
+	}
+	return chacha20poly1305.NewX(key)
+}
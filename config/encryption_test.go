@@ -0,0 +1,45 @@
+package config
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestEncryptDecrypt(t *testing.T) {
+	plaintext := []byte("device_id = 'id'\n")
+	encrypted, err := Encrypt(plaintext, "passphrase")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !IsEncrypted(encrypted) || bytes.Contains(encrypted, plaintext) {
+		t.Fatal("configuration not encrypted")
+	}
+	decrypted, err := Decrypt(encrypted, "passphrase")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(plaintext, decrypted) {
+		t.Fatalf("expected %q, got %q", plaintext, decrypted)
+	}
+}
+
+func TestDecryptWrongPassphrase(t *testing.T) {
+	encrypted, err := Encrypt([]byte("device_id = 'id'\n"), "passphrase")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Decrypt(encrypted, "wrong"); err == nil {
+		t.Fatal("expected error")
+	}
+}
+
+func TestDecryptTampered(t *testing.T) {
+	encrypted, err := Encrypt([]byte("device_id = 'id'\n"), "passphrase")
+	if err != nil {
+		t.Fatal(err)
+	}
+	encrypted[len(encryptedHeader)+10] ^= 1
+	if _, err := Decrypt(encrypted, "passphrase"); err == nil {
+		t.Fatal("expected error")
+	}
+}
@@ -27,6 +27,108 @@ var (
 // DefaultApiService DefaultApi service
 type DefaultApiService service
 
+type ApiDeleteBoundDeviceRequest struct {
+	ctx _context.Context
+	ApiService *DefaultApiService
+	sourceDeviceId string
+	apiVersion string
+	boundDeviceId string
+}
+
+
+func (r ApiDeleteBoundDeviceRequest) Execute() (*_nethttp.Response, error) {
+	return r.ApiService.DeleteBoundDeviceExecute(r)
+}
+
+/*
+ * DeleteBoundDevice DeleteBoundDevice
+ * @param ctx _context.Context - for authentication, logging, cancellation, deadlines, tracing, etc. Passed from http.Request or context.Background().
+ * @param sourceDeviceId
+ * @param apiVersion
+ * @param boundDeviceId
+ * @return ApiDeleteBoundDeviceRequest
+ */
+func (a *DefaultApiService) DeleteBoundDevice(ctx _context.Context, sourceDeviceId string, apiVersion string, boundDeviceId string) ApiDeleteBoundDeviceRequest {
+	return ApiDeleteBoundDeviceRequest{
+		ApiService: a,
+		ctx: ctx,
+		sourceDeviceId: sourceDeviceId,
+		apiVersion: apiVersion,
+		boundDeviceId: boundDeviceId,
+	}
+}
+
+/*
+ * Execute executes the request
+ */
+func (a *DefaultApiService) DeleteBoundDeviceExecute(r ApiDeleteBoundDeviceRequest) (*_nethttp.Response, error) {
+	var (
+		localVarHTTPMethod   = _nethttp.MethodDelete
+		localVarPostBody     interface{}
+		localVarFormFileName string
+		localVarFileName     string
+		localVarFileBytes    []byte
+	)
+
+	localBasePath, err := a.client.cfg.ServerURLWithContext(r.ctx, "DefaultApiService.DeleteBoundDevice")
+	if err != nil {
+		return nil, GenericOpenAPIError{error: err.Error()}
+	}
+
+	localVarPath := localBasePath + "/{apiVersion}/reg/{sourceDeviceId}/account/reg/{boundDeviceId}"
+	localVarPath = strings.Replace(localVarPath, "{"+"sourceDeviceId"+"}", _neturl.PathEscape(parameterToString(r.sourceDeviceId, "")), -1)
+	localVarPath = strings.Replace(localVarPath, "{"+"apiVersion"+"}", _neturl.PathEscape(parameterToString(r.apiVersion, "")), -1)
+	localVarPath = strings.Replace(localVarPath, "{"+"boundDeviceId"+"}", _neturl.PathEscape(parameterToString(r.boundDeviceId, "")), -1)
+
+	localVarHeaderParams := make(map[string]string)
+	localVarQueryParams := _neturl.Values{}
+	localVarFormParams := _neturl.Values{}
+
+	// to determine the Content-Type header
+	localVarHTTPContentTypes := []string{}
+
+	// set Content-Type header
+	localVarHTTPContentType := selectHeaderContentType(localVarHTTPContentTypes)
+	if localVarHTTPContentType != "" {
+		localVarHeaderParams["Content-Type"] = localVarHTTPContentType
+	}
+
+	// to determine the Accept header
+	localVarHTTPHeaderAccepts := []string{}
+
+	// set Accept header
+	localVarHTTPHeaderAccept := selectHeaderAccept(localVarHTTPHeaderAccepts)
+	if localVarHTTPHeaderAccept != "" {
+		localVarHeaderParams["Accept"] = localVarHTTPHeaderAccept
+	}
+	req, err := a.client.prepareRequest(r.ctx, localVarPath, localVarHTTPMethod, localVarPostBody, localVarHeaderParams, localVarQueryParams, localVarFormParams, localVarFormFileName, localVarFileName, localVarFileBytes)
+	if err != nil {
+		return nil, err
+	}
+
+	localVarHTTPResponse, err := a.client.callAPI(req)
+	if err != nil || localVarHTTPResponse == nil {
+		return localVarHTTPResponse, err
+	}
+
+	localVarBody, err := _ioutil.ReadAll(localVarHTTPResponse.Body)
+	localVarHTTPResponse.Body.Close()
+	localVarHTTPResponse.Body = _ioutil.NopCloser(bytes.NewBuffer(localVarBody))
+	if err != nil {
+		return localVarHTTPResponse, err
+	}
+
+	if localVarHTTPResponse.StatusCode >= 300 {
+		newErr := GenericOpenAPIError{
+			body:  localVarBody,
+			error: localVarHTTPResponse.Status,
+		}
+		return localVarHTTPResponse, newErr
+	}
+
+	return localVarHTTPResponse, nil
+}
+
 type ApiGetAccountRequest struct {
 	ctx _context.Context
 	ApiService *DefaultApiService
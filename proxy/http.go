@@ -0,0 +1,142 @@
+package proxy
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Headers which only apply to a single connection, and aren't forwarded.
+var hopByHopHeaders = []string{
+	"Connection", "Keep-Alive", "Proxy-Authenticate", "Proxy-Authorization", "Proxy-Connection",
+	"Te", "Trailer", "Transfer-Encoding", "Upgrade",
+}
+
+// An HTTP proxy without authentication, supporting CONNECT tunnels and plain HTTP requests.
+type HTTPServer struct {
+	network   Network
+	transport *http.Transport
+}
+
+func NewHTTPServer(network Network) *HTTPServer {
+	return &HTTPServer{
+		network: network,
+		transport: &http.Transport{
+			DialContext: network.DialContext,
+		},
+	}
+}
+
+// Serves requests from the listener until the context is done.
+func (s *HTTPServer) Serve(ctx context.Context, listener net.Listener) error {
+	server := &http.Server{
+		Handler:  s,
+		ErrorLog: log.New(log.Writer(), "HTTP proxy: ", log.Flags()),
+	}
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			_ = server.Close()
+		case <-done:
+		}
+	}()
+	if err := server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+func (s *HTTPServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodConnect {
+		s.connect(w, r)
+		return
+	}
+	if !r.URL.IsAbs() {
+		http.Error(w, "this is a proxy, requests must use absolute URLs", http.StatusBadRequest)
+		return
+	}
+
+	outgoing := r.Clone(r.Context())
+	outgoing.RequestURI = ""
+	removeHopByHopHeaders(outgoing.Header)
+	response, err := s.transport.RoundTrip(outgoing)
+	if err != nil {
+		log.Println("HTTP proxy:", err)
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	defer response.Body.Close()
+	removeHopByHopHeaders(response.Header)
+	for key, values := range response.Header {
+		for _, value := range values {
+			w.Header().Add(key, value)
+		}
+	}
+	w.WriteHeader(response.StatusCode)
+	_, _ = io.Copy(w, response.Body)
+}
+
+func (s *HTTPServer) connect(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), DialTimeout)
+	remote, err := s.network.DialContext(ctx, "tcp", r.Host)
+	cancel()
+	if err != nil {
+		log.Println("HTTP proxy:", err)
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	hijacker, ok := w.(http.Hijacker)
+	if !ok {
+		_ = remote.Close()
+		http.Error(w, "connection can't be hijacked", http.StatusInternalServerError)
+		return
+	}
+	c, buffer, err := hijacker.Hijack()
+	if err != nil {
+		_ = remote.Close()
+		log.Println("HTTP proxy:", err)
+		return
+	}
+	if _, err := c.Write([]byte("HTTP/1.1 200 Connection established\r\n\r\n")); err != nil {
+		_ = remote.Close()
+		_ = c.Close()
+		return
+	}
+	// the client may have sent data along with the request
+	relay(&bufferedConn{Conn: c, reader: buffer.Reader}, remote)
+}
+
+func removeHopByHopHeaders(header http.Header) {
+	for _, field := range header.Values("Connection") {
+		for _, key := range strings.Split(field, ",") {
+			header.Del(strings.TrimSpace(key))
+		}
+	}
+	for _, key := range hopByHopHeaders {
+		header.Del(key)
+	}
+}
+
+type bufferedConn struct {
+	net.Conn
+	reader *bufio.Reader
+}
+
+func (c *bufferedConn) Read(p []byte) (int, error) {
+	return c.reader.Read(p)
+}
+
+func (c *bufferedConn) CloseWrite() error {
+	if closer, ok := c.Conn.(interface{ CloseWrite() error }); ok {
+		return closer.CloseWrite()
+	}
+	return errors.New("half-close not supported")
+}
@@ -0,0 +1,96 @@
+package proxy
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strconv"
+	"testing"
+
+	"github.com/ViRb3/wgcf/v2/wireguard/wgtest"
+)
+
+func TestHTTPProxy(t *testing.T) {
+	network, peer := newTestNetwork(t)
+	listener, err := peer.Net.ListenTCPAddrPort(netip.AddrPortFrom(wgtest.PeerAddress, httpPort))
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Proxy-Connection") != "" {
+			t.Error("hop-by-hop header was forwarded")
+		}
+		_, _ = w.Write([]byte("hello from " + r.Host))
+	})}
+	go func() { _ = server.Serve(listener) }()
+	defer server.Close()
+	proxyAddress := startTestServer(t, NewHTTPServer(network).Serve)
+
+	proxyURL, _ := url.Parse("http://" + proxyAddress)
+	client := &http.Client{Transport: &http.Transport{Proxy: http.ProxyURL(proxyURL)}}
+	request, _ := http.NewRequest(http.MethodGet, "http://"+wgtest.PeerAddress.String()+"/", nil)
+	request.Header.Set("Proxy-Connection", "keep-alive")
+	response, err := client.Do(request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer response.Body.Close()
+	body, err := io.ReadAll(response.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if response.StatusCode != http.StatusOK || string(body) != "hello from "+wgtest.PeerAddress.String() {
+		t.Fatalf("unexpected response %d: %s", response.StatusCode, body)
+	}
+}
+
+func TestHTTPProxyConnect(t *testing.T) {
+	network, _ := newTestNetwork(t)
+	proxyAddress := startTestServer(t, NewHTTPServer(network).Serve)
+
+	for _, test := range []struct {
+		port   int
+		status int
+	}{
+		{echoPort, http.StatusOK},
+		{closedPort, http.StatusBadGateway},
+	} {
+		c, err := net.Dial("tcp", proxyAddress)
+		if err != nil {
+			t.Fatal(err)
+		}
+		target := net.JoinHostPort(wgtest.PeerAddress.String(), strconv.Itoa(test.port))
+		if _, err := c.Write([]byte("CONNECT " + target + " HTTP/1.1\r\nHost: " + target + "\r\n\r\n")); err != nil {
+			t.Fatal(err)
+		}
+		reader := bufio.NewReader(c)
+		response, err := http.ReadResponse(reader, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if response.StatusCode != test.status {
+			t.Fatalf("unexpected status %d for %s", response.StatusCode, target)
+		}
+		if test.status == http.StatusOK {
+			testEcho(t, &bufferedConn{Conn: c, reader: reader}, "hello through the tunnel")
+		}
+		c.Close()
+	}
+}
+
+func TestHTTPProxyRelativeURL(t *testing.T) {
+	network, _ := newTestNetwork(t)
+	proxyAddress := startTestServer(t, NewHTTPServer(network).Serve)
+
+	response, err := http.Get("http://" + proxyAddress + "/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	response.Body.Close()
+	if response.StatusCode != http.StatusBadRequest {
+		t.Fatalf("unexpected status %d", response.StatusCode)
+	}
+}
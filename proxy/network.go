@@ -0,0 +1,104 @@
+// Package proxy forwards SOCKS5 and HTTP proxy connections through a network, usually the
+// userspace network stack of a WireGuard tunnel.
+package proxy
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/netip"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"golang.zx2c4.com/wireguard/tun/netstack"
+)
+
+// Maximum time to establish an outgoing connection.
+const DialTimeout = 30 * time.Second
+
+// The network that connections are forwarded through.
+type Network interface {
+	DialContext(ctx context.Context, network, address string) (net.Conn, error)
+	LookupContextHost(ctx context.Context, host string) ([]string, error)
+}
+
+type netstackNetwork struct {
+	*netstack.Net
+}
+
+// Forwards through a userspace network stack, resolving names with its DNS servers.
+func NewNetstackNetwork(tunNet *netstack.Net) Network {
+	return &netstackNetwork{tunNet}
+}
+
+// resolves the host through the network, preferring IPv4
+func resolveUDPAddr(ctx context.Context, network Network, host string, port uint16) (netip.AddrPort, error) {
+	if addr, err := netip.ParseAddr(host); err == nil {
+		return netip.AddrPortFrom(addr.Unmap(), port), nil
+	}
+	hosts, err := network.LookupContextHost(ctx, host)
+	if err != nil {
+		return netip.AddrPort{}, err
+	}
+	var result netip.Addr
+	for _, host := range hosts {
+		addr, err := netip.ParseAddr(host)
+		if err != nil {
+			continue
+		}
+		if addr = addr.Unmap(); addr.Is4() {
+			return netip.AddrPortFrom(addr, port), nil
+		}
+		if !result.IsValid() {
+			result = addr
+		}
+	}
+	if !result.IsValid() {
+		return netip.AddrPort{}, errors.Errorf("no addresses found for %s", host)
+	}
+	return netip.AddrPortFrom(result, port), nil
+}
+
+// copies data both ways until both sides are done, then closes them
+func relay(a net.Conn, b net.Conn) {
+	var wg sync.WaitGroup
+	wg.Add(2)
+	pipe := func(dst net.Conn, src net.Conn) {
+		defer wg.Done()
+		_, _ = io.Copy(dst, src)
+		if closer, ok := dst.(interface{ CloseWrite() error }); !ok || closer.CloseWrite() != nil {
+			// without a half-close, the other direction has to be stopped too
+			_ = dst.Close()
+			_ = src.Close()
+		}
+	}
+	go pipe(a, b)
+	go pipe(b, a)
+	wg.Wait()
+	_ = a.Close()
+	_ = b.Close()
+}
+
+// closes the listener once the context is done, returning nil for the resulting error
+func serve(ctx context.Context, listener net.Listener, handle func(net.Conn)) error {
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			_ = listener.Close()
+		case <-done:
+		}
+	}()
+	for {
+		c, err := listener.Accept()
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+		go handle(c)
+	}
+}
@@ -0,0 +1,113 @@
+package proxy
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/ViRb3/wgcf/v2/wireguard"
+	"github.com/ViRb3/wgcf/v2/wireguard/wgtest"
+)
+
+// Ports the peer serves inside the tunnel.
+const (
+	echoPort   = 7
+	closedPort = 9
+	httpPort   = 80
+)
+
+// returns a network tunnelled to an in-process peer, which echoes TCP and UDP on echoPort
+func newTestNetwork(t *testing.T) (Network, *wgtest.Peer) {
+	peer, err := wgtest.NewPeer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(peer.Close)
+	tunnel, err := wireguard.NewNetstackTunnel(&wireguard.ProfileData{
+		PrivateKey:          peer.ClientPrivateKey,
+		Address1:            wgtest.ClientAddress.String(),
+		PublicKey:           peer.PublicKey,
+		Endpoint:            peer.Endpoint,
+		PersistentKeepalive: 25,
+		DNS:                 []string{},
+		IPMode:              wireguard.IPModeV4,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(tunnel.Close)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := tunnel.WaitHandshake(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	listener, err := peer.Net.ListenTCPAddrPort(netip.AddrPortFrom(wgtest.PeerAddress, echoPort))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+	go func() {
+		for {
+			c, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer c.Close()
+				_, _ = io.Copy(c, c)
+			}()
+		}
+	}()
+	packetConn, err := peer.Net.ListenUDPAddrPort(netip.AddrPortFrom(wgtest.PeerAddress, echoPort))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { packetConn.Close() })
+	go func() {
+		buffer := make([]byte, maxDatagramSize)
+		for {
+			n, from, err := packetConn.ReadFrom(buffer)
+			if err != nil {
+				return
+			}
+			_, _ = packetConn.WriteTo(buffer[:n], from)
+		}
+	}()
+	return NewNetstackNetwork(tunnel.Net), peer
+}
+
+// starts serving on localhost, returning the address
+func startTestServer(t *testing.T, serve func(context.Context, net.Listener) error) string {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- serve(ctx, listener) }()
+	t.Cleanup(func() {
+		cancel()
+		if err := <-done; err != nil {
+			t.Error(err)
+		}
+	})
+	return listener.Addr().String()
+}
+
+func testEcho(t *testing.T, c net.Conn, message string) {
+	t.Helper()
+	if err := c.SetDeadline(time.Now().Add(10 * time.Second)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Write([]byte(message)); err != nil {
+		t.Fatal(err)
+	}
+	reply := make([]byte, len(message))
+	if _, err := io.ReadFull(c, reply); err != nil || string(reply) != message {
+		t.Fatalf("unexpected reply %q: %v", reply, err)
+	}
+}
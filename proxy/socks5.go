@@ -0,0 +1,414 @@
+package proxy
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"io"
+	"log"
+	"net"
+	"net/netip"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// https://www.rfc-editor.org/rfc/rfc1928
+const (
+	socks5Version = 5
+
+	socks5NoAuth       = 0
+	socks5NoAcceptable = 0xff
+
+	socks5Connect      = 1
+	socks5UDPAssociate = 3
+
+	socks5IPv4   = 1
+	socks5Domain = 3
+	socks5IPv6   = 4
+
+	socks5Succeeded          = 0
+	socks5GeneralFailure     = 1
+	socks5NetworkUnreachable = 3
+	socks5HostUnreachable    = 4
+	socks5ConnectionRefused  = 5
+	socks5CommandUnsupported = 7
+	socks5AddressUnsupported = 8
+)
+
+const maxDatagramSize = 65535
+
+// A SOCKS5 proxy without authentication, supporting CONNECT and UDP ASSOCIATE.
+type SOCKS5Server struct {
+	network Network
+}
+
+func NewSOCKS5Server(network Network) *SOCKS5Server {
+	return &SOCKS5Server{network: network}
+}
+
+// Serves connections from the listener until the context is done.
+func (s *SOCKS5Server) Serve(ctx context.Context, listener net.Listener) error {
+	return serve(ctx, listener, func(c net.Conn) {
+		if err := s.handle(ctx, c); err != nil {
+			log.Println("SOCKS5:", err)
+		}
+	})
+}
+
+func (s *SOCKS5Server) handle(ctx context.Context, c net.Conn) error {
+	defer c.Close()
+	if err := socks5Negotiate(c); err != nil {
+		return err
+	}
+
+	header := make([]byte, 3)
+	if _, err := io.ReadFull(c, header); err != nil {
+		return err
+	}
+	if header[0] != socks5Version {
+		return errors.Errorf("unsupported version %d", header[0])
+	}
+	host, port, err := readSOCKS5Address(c)
+	if err != nil {
+		if errors.Is(err, errSOCKS5AddressUnsupported) {
+			_ = writeSOCKS5Reply(c, socks5AddressUnsupported, netip.AddrPort{})
+		}
+		return err
+	}
+
+	switch header[1] {
+	case socks5Connect:
+		return s.connect(ctx, c, net.JoinHostPort(host, strconv.Itoa(int(port))))
+	case socks5UDPAssociate:
+		return s.associate(ctx, c)
+	default:
+		_ = writeSOCKS5Reply(c, socks5CommandUnsupported, netip.AddrPort{})
+		return errors.Errorf("unsupported command %d", header[1])
+	}
+}
+
+// only no authentication is supported
+func socks5Negotiate(c net.Conn) error {
+	header := make([]byte, 2)
+	if _, err := io.ReadFull(c, header); err != nil {
+		return err
+	}
+	if header[0] != socks5Version {
+		return errors.Errorf("unsupported version %d", header[0])
+	}
+	methods := make([]byte, header[1])
+	if _, err := io.ReadFull(c, methods); err != nil {
+		return err
+	}
+	for _, method := range methods {
+		if method == socks5NoAuth {
+			_, err := c.Write([]byte{socks5Version, socks5NoAuth})
+			return err
+		}
+	}
+	_, _ = c.Write([]byte{socks5Version, socks5NoAcceptable})
+	return errors.New("no supported authentication method, only no authentication is supported")
+}
+
+func (s *SOCKS5Server) connect(ctx context.Context, c net.Conn, address string) error {
+	dialCtx, cancel := context.WithTimeout(ctx, DialTimeout)
+	remote, err := s.network.DialContext(dialCtx, "tcp", address)
+	cancel()
+	if err != nil {
+		_ = writeSOCKS5Reply(c, getSOCKS5ReplyCode(err), netip.AddrPort{})
+		return errors.WithMessage(err, "connect "+address)
+	}
+	if err := writeSOCKS5Reply(c, socks5Succeeded, netip.AddrPort{}); err != nil {
+		_ = remote.Close()
+		return err
+	}
+	relay(c, remote)
+	return nil
+}
+
+func getSOCKS5ReplyCode(err error) byte {
+	message := err.Error()
+	switch {
+	case strings.Contains(message, "refused"):
+		return socks5ConnectionRefused
+	case strings.Contains(message, "unreachable"):
+		return socks5NetworkUnreachable
+	case errors.Is(err, context.DeadlineExceeded) || strings.Contains(message, "timed out") || strings.Contains(message, "no such host"):
+		return socks5HostUnreachable
+	default:
+		return socks5GeneralFailure
+	}
+}
+
+// relays datagrams between the client and the network until the control connection closes
+func (s *SOCKS5Server) associate(ctx context.Context, c net.Conn) error {
+	clientAddr, err := netip.ParseAddrPort(c.RemoteAddr().String())
+	if err != nil {
+		return err
+	}
+	localAddr, err := netip.ParseAddrPort(c.LocalAddr().String())
+	if err != nil {
+		return err
+	}
+	local, err := net.ListenUDP("udp", net.UDPAddrFromAddrPort(netip.AddrPortFrom(localAddr.Addr(), 0)))
+	if err != nil {
+		_ = writeSOCKS5Reply(c, socks5GeneralFailure, netip.AddrPort{})
+		return err
+	}
+	association := &udpAssociation{
+		network:  s.network,
+		local:    local,
+		clientIP: clientAddr.Addr().Unmap(),
+		remotes:  map[string]*udpRemote{},
+	}
+	defer association.close()
+	if err := writeSOCKS5Reply(c, socks5Succeeded, local.LocalAddr().(*net.UDPAddr).AddrPort()); err != nil {
+		return err
+	}
+
+	go association.serve(ctx)
+	// the association lasts as long as the control connection
+	_, _ = io.Copy(io.Discard, c)
+	return nil
+}
+
+type udpAssociation struct {
+	network  Network
+	local    *net.UDPConn
+	clientIP netip.Addr
+
+	mu sync.Mutex
+	// the last address the client sent from
+	client netip.AddrPort
+	// a socket for each destination, so replies are known to come from it
+	remotes map[string]*udpRemote
+	closed  bool
+}
+
+// Limits of a UDP association, so a single client can't exhaust the sockets of the network.
+var (
+	// a destination's socket is closed after this long without datagrams in either direction
+	udpIdleTimeout = 2 * time.Minute
+	maxUDPRemotes  = 256
+	// datagrams queued while the socket is set up, more are dropped
+	udpQueueSize = 64
+)
+
+type udpRemote struct {
+	queue chan []byte
+	// nil until the destination is resolved and dialed
+	conn net.Conn
+}
+
+func (a *udpAssociation) serve(ctx context.Context) {
+	buffer := make([]byte, maxDatagramSize)
+	for {
+		n, from, err := a.local.ReadFromUDPAddrPort(buffer)
+		if err != nil {
+			return
+		}
+		// datagrams from anyone else are dropped, as the relay may be reachable from the network
+		if from.Addr().Unmap() != a.clientIP {
+			continue
+		}
+		if err := a.forward(ctx, from, buffer[:n]); err != nil {
+			log.Println("SOCKS5: UDP:", err)
+		}
+	}
+}
+
+// queues the datagram for its destination, so a slow destination doesn't hold up the others
+func (a *udpAssociation) forward(ctx context.Context, from netip.AddrPort, datagram []byte) error {
+	if len(datagram) < 4 {
+		return errors.New("datagram too short")
+	}
+	// fragmentation is optional and not supported
+	if datagram[2] != 0 {
+		return nil
+	}
+	reader := bytes.NewReader(datagram[3:])
+	host, port, err := readSOCKS5Address(reader)
+	if err != nil {
+		return err
+	}
+	remote, err := a.getRemote(ctx, from, host, port)
+	if err != nil {
+		return err
+	}
+	select {
+	case remote.queue <- bytes.Clone(datagram[len(datagram)-reader.Len():]):
+	default:
+		// like any congested UDP path
+	}
+	return nil
+}
+
+// returns the remote of the destination, setting it up on first use
+func (a *udpAssociation) getRemote(ctx context.Context, from netip.AddrPort, host string, port uint16) (*udpRemote, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.closed {
+		return nil, net.ErrClosed
+	}
+	a.client = from
+	key := net.JoinHostPort(host, strconv.Itoa(int(port)))
+	if remote, ok := a.remotes[key]; ok {
+		return remote, nil
+	}
+	if len(a.remotes) >= maxUDPRemotes {
+		return nil, errors.Errorf("too many destinations, dropping datagram to %s", key)
+	}
+	remote := &udpRemote{queue: make(chan []byte, udpQueueSize)}
+	a.remotes[key] = remote
+	go a.runRemote(ctx, key, host, port, remote)
+	return remote, nil
+}
+
+// resolves and dials the destination, then relays datagrams until it's idle or the association closes
+func (a *udpAssociation) runRemote(ctx context.Context, key string, host string, port uint16, remote *udpRemote) {
+	defer a.removeRemote(key, remote)
+	dialCtx, cancel := context.WithTimeout(ctx, DialTimeout)
+	destination, err := resolveUDPAddr(dialCtx, a.network, host, port)
+	var conn net.Conn
+	if err == nil {
+		conn, err = a.network.DialContext(dialCtx, "udp", destination.String())
+	}
+	cancel()
+	if err != nil {
+		log.Println("SOCKS5: UDP:", err)
+		return
+	}
+	defer conn.Close()
+	if !a.setConn(remote, conn) {
+		return
+	}
+
+	_ = conn.SetReadDeadline(time.Now().Add(udpIdleTimeout))
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		for {
+			select {
+			case datagram := <-remote.queue:
+				_ = conn.SetReadDeadline(time.Now().Add(udpIdleTimeout))
+				_, _ = conn.Write(datagram)
+			case <-done:
+				return
+			}
+		}
+	}()
+	a.reply(destination, conn)
+}
+
+// returns false if the association was closed meanwhile
+func (a *udpAssociation) setConn(remote *udpRemote, conn net.Conn) bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.closed {
+		return false
+	}
+	remote.conn = conn
+	return true
+}
+
+func (a *udpAssociation) removeRemote(key string, remote *udpRemote) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.remotes[key] == remote {
+		delete(a.remotes, key)
+	}
+}
+
+// sends the datagrams received from the destination back to the client, until it's idle
+func (a *udpAssociation) reply(destination netip.AddrPort, remote net.Conn) {
+	header := append([]byte{0, 0, 0}, encodeSOCKS5Address(destination)...)
+	buffer := make([]byte, maxDatagramSize)
+	for {
+		n, err := remote.Read(buffer)
+		if err != nil {
+			return
+		}
+		_ = remote.SetReadDeadline(time.Now().Add(udpIdleTimeout))
+		a.mu.Lock()
+		client := a.client
+		a.mu.Unlock()
+		_, _ = a.local.WriteToUDPAddrPort(append(header, buffer[:n]...), client)
+	}
+}
+
+func (a *udpAssociation) close() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.closed = true
+	_ = a.local.Close()
+	for _, remote := range a.remotes {
+		if remote.conn != nil {
+			_ = remote.conn.Close()
+		}
+	}
+}
+
+var errSOCKS5AddressUnsupported = errors.New("unsupported address type")
+
+func readSOCKS5Address(r io.Reader) (string, uint16, error) {
+	addressType := make([]byte, 1)
+	if _, err := io.ReadFull(r, addressType); err != nil {
+		return "", 0, err
+	}
+	var host string
+	switch addressType[0] {
+	case socks5IPv4, socks5IPv6:
+		length := 4
+		if addressType[0] == socks5IPv6 {
+			length = 16
+		}
+		ip := make([]byte, length)
+		if _, err := io.ReadFull(r, ip); err != nil {
+			return "", 0, err
+		}
+		addr, _ := netip.AddrFromSlice(ip)
+		host = addr.String()
+	case socks5Domain:
+		length := make([]byte, 1)
+		if _, err := io.ReadFull(r, length); err != nil {
+			return "", 0, err
+		}
+		domain := make([]byte, length[0])
+		if _, err := io.ReadFull(r, domain); err != nil {
+			return "", 0, err
+		}
+		host = string(domain)
+	default:
+		return "", 0, errSOCKS5AddressUnsupported
+	}
+	port := make([]byte, 2)
+	if _, err := io.ReadFull(r, port); err != nil {
+		return "", 0, err
+	}
+	return host, binary.BigEndian.Uint16(port), nil
+}
+
+func encodeSOCKS5Address(addr netip.AddrPort) []byte {
+	var result []byte
+	if ip := addr.Addr().Unmap(); ip.Is4() {
+		ip4 := ip.As4()
+		result = append([]byte{socks5IPv4}, ip4[:]...)
+	} else {
+		ip16 := ip.As16()
+		result = append([]byte{socks5IPv6}, ip16[:]...)
+	}
+	return binary.BigEndian.AppendUint16(result, addr.Port())
+}
+
+// an invalid address is sent as 0.0.0.0:0
+func writeSOCKS5Reply(c net.Conn, code byte, bound netip.AddrPort) error {
+	if !bound.IsValid() {
+		bound = netip.AddrPortFrom(netip.IPv4Unspecified(), 0)
+	}
+	_, err := c.Write(append([]byte{socks5Version, code, 0}, encodeSOCKS5Address(bound)...))
+	return err
+}
@@ -0,0 +1,221 @@
+package proxy
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"io"
+	"net"
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/ViRb3/wgcf/v2/wireguard/wgtest"
+)
+
+// negotiates with the proxy and sends the request, returning the reply code and bound address
+func socks5Request(t *testing.T, c net.Conn, command byte, address netip.AddrPort) (byte, netip.AddrPort) {
+	t.Helper()
+	if err := c.SetDeadline(time.Now().Add(10 * time.Second)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Write([]byte{socks5Version, 1, socks5NoAuth}); err != nil {
+		t.Fatal(err)
+	}
+	method := make([]byte, 2)
+	if _, err := io.ReadFull(c, method); err != nil || method[1] != socks5NoAuth {
+		t.Fatalf("unexpected method %v: %v", method, err)
+	}
+	if _, err := c.Write(append([]byte{socks5Version, command, 0}, encodeSOCKS5Address(address)...)); err != nil {
+		t.Fatal(err)
+	}
+	reply := make([]byte, 3)
+	if _, err := io.ReadFull(c, reply); err != nil {
+		t.Fatal(err)
+	}
+	host, port, err := readSOCKS5Address(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return reply[1], netip.AddrPortFrom(netip.MustParseAddr(host), port)
+}
+
+func TestSOCKS5Connect(t *testing.T) {
+	network, _ := newTestNetwork(t)
+	proxyAddress := startTestServer(t, NewSOCKS5Server(network).Serve)
+
+	c, err := net.Dial("tcp", proxyAddress)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	if code, _ := socks5Request(t, c, socks5Connect, netip.AddrPortFrom(wgtest.PeerAddress, echoPort)); code != socks5Succeeded {
+		t.Fatalf("unexpected reply code %d", code)
+	}
+	testEcho(t, c, "hello through the tunnel")
+}
+
+func TestSOCKS5ConnectRefused(t *testing.T) {
+	network, _ := newTestNetwork(t)
+	proxyAddress := startTestServer(t, NewSOCKS5Server(network).Serve)
+
+	c, err := net.Dial("tcp", proxyAddress)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	if code, _ := socks5Request(t, c, socks5Connect, netip.AddrPortFrom(wgtest.PeerAddress, closedPort)); code != socks5ConnectionRefused {
+		t.Fatalf("unexpected reply code %d", code)
+	}
+}
+
+func TestSOCKS5UDPAssociate(t *testing.T) {
+	network, _ := newTestNetwork(t)
+	proxyAddress := startTestServer(t, NewSOCKS5Server(network).Serve)
+
+	c, err := net.Dial("tcp", proxyAddress)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	code, relayAddress := socks5Request(t, c, socks5UDPAssociate, netip.AddrPortFrom(netip.IPv4Unspecified(), 0))
+	if code != socks5Succeeded {
+		t.Fatalf("unexpected reply code %d", code)
+	}
+
+	udp, err := net.DialUDP("udp", nil, net.UDPAddrFromAddrPort(relayAddress))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer udp.Close()
+	if err := udp.SetDeadline(time.Now().Add(10 * time.Second)); err != nil {
+		t.Fatal(err)
+	}
+	destination := netip.AddrPortFrom(wgtest.PeerAddress, echoPort)
+	header := append([]byte{0, 0, 0}, encodeSOCKS5Address(destination)...)
+	if _, err := udp.Write(append(header, []byte("hello")...)); err != nil {
+		t.Fatal(err)
+	}
+	reply := make([]byte, maxDatagramSize)
+	n, err := udp.Read(reply)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(reply[:n], append(header, []byte("hello")...)) {
+		t.Fatalf("unexpected reply %v", reply[:n])
+	}
+}
+
+// a network whose name lookups hang until the context is done
+type slowLookupNetwork struct {
+	Network
+}
+
+func (n *slowLookupNetwork) LookupContextHost(ctx context.Context, host string) ([]string, error) {
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+func TestSOCKS5UDPLimits(t *testing.T) {
+	defer func(timeout time.Duration, max int) { udpIdleTimeout, maxUDPRemotes = timeout, max }(udpIdleTimeout, maxUDPRemotes)
+	udpIdleTimeout = 300 * time.Millisecond
+	maxUDPRemotes = 2
+
+	network, _ := newTestNetwork(t)
+	local, err := net.ListenUDP("udp", net.UDPAddrFromAddrPort(netip.MustParseAddrPort("127.0.0.1:0")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	association := &udpAssociation{
+		network:  &slowLookupNetwork{network},
+		local:    local,
+		clientIP: netip.MustParseAddr("127.0.0.1"),
+		remotes:  map[string]*udpRemote{},
+	}
+	defer association.close()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go association.serve(ctx)
+	remotes := func() int {
+		association.mu.Lock()
+		defer association.mu.Unlock()
+		return len(association.remotes)
+	}
+
+	udp, err := net.DialUDP("udp", nil, local.LocalAddr().(*net.UDPAddr))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer udp.Close()
+	if err := udp.SetDeadline(time.Now().Add(10 * time.Second)); err != nil {
+		t.Fatal(err)
+	}
+	send := func(header []byte, message string) {
+		if _, err := udp.Write(append(header, message...)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// a destination which never resolves doesn't hold up the others
+	slowHeader := []byte{0, 0, 0, socks5Domain, byte(len("slow.example")), 's', 'l', 'o', 'w', '.', 'e', 'x', 'a', 'm', 'p', 'l', 'e', 0, echoPort}
+	send(slowHeader, "lost")
+	echoHeader := append([]byte{0, 0, 0}, encodeSOCKS5Address(netip.AddrPortFrom(wgtest.PeerAddress, echoPort))...)
+	send(echoHeader, "hello")
+	reply := make([]byte, maxDatagramSize)
+	n, err := udp.Read(reply)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(reply[:n], append(echoHeader, "hello"...)) {
+		t.Fatalf("unexpected reply %v", reply[:n])
+	}
+
+	// further destinations are dropped
+	send(append([]byte{0, 0, 0}, encodeSOCKS5Address(netip.AddrPortFrom(wgtest.PeerAddress, closedPort))...), "dropped")
+	time.Sleep(50 * time.Millisecond)
+	if count := remotes(); count != 2 {
+		t.Fatalf("expected 2 destinations, got %d", count)
+	}
+
+	// the idle destination is closed, the unresolved one is still pending
+	deadline := time.Now().Add(5 * time.Second)
+	for remotes() != 1 {
+		if time.Now().After(deadline) {
+			t.Fatalf("idle destination was not closed, %d destinations", remotes())
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
+
+func TestSOCKS5UnsupportedCommand(t *testing.T) {
+	network, _ := newTestNetwork(t)
+	proxyAddress := startTestServer(t, NewSOCKS5Server(network).Serve)
+
+	c, err := net.Dial("tcp", proxyAddress)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	// BIND
+	if code, _ := socks5Request(t, c, 2, netip.AddrPortFrom(wgtest.PeerAddress, echoPort)); code != socks5CommandUnsupported {
+		t.Fatalf("unexpected reply code %d", code)
+	}
+}
+
+func TestSOCKS5Address(t *testing.T) {
+	for _, address := range []string{"10.0.0.1:80", "[2606:4700::1111]:443"} {
+		addr := netip.MustParseAddrPort(address)
+		host, port, err := readSOCKS5Address(bytes.NewReader(encodeSOCKS5Address(addr)))
+		if err != nil || netip.AddrPortFrom(netip.MustParseAddr(host), port) != addr {
+			t.Fatalf("unexpected address %s:%d for %s: %v", host, port, address, err)
+		}
+	}
+	domain := append([]byte{socks5Domain, 11}, []byte("example.com")...)
+	domain = binary.BigEndian.AppendUint16(domain, 443)
+	if host, port, err := readSOCKS5Address(bytes.NewReader(domain)); err != nil || host != "example.com" || port != 443 {
+		t.Fatalf("unexpected address %s:%d: %v", host, port, err)
+	}
+	if _, _, err := readSOCKS5Address(bytes.NewReader([]byte{2})); err != errSOCKS5AddressUnsupported {
+		t.Fatalf("expected unsupported address error, got %v", err)
+	}
+}
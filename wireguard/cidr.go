@@ -0,0 +1,72 @@
+package wireguard
+
+import (
+	"net/netip"
+	"sort"
+)
+
+var (
+	AllIPv4 = netip.MustParsePrefix("0.0.0.0/0")
+	AllIPv6 = netip.MustParsePrefix("::/0")
+)
+
+// Parses a CIDR, or a single address which is treated as a host prefix.
+func ParsePrefix(value string) (netip.Prefix, error) {
+	if prefix, err := netip.ParsePrefix(value); err == nil {
+		return prefix.Masked(), nil
+	}
+	addr, err := netip.ParseAddr(value)
+	if err != nil {
+		return netip.Prefix{}, err
+	}
+	return netip.PrefixFrom(addr, addr.BitLen()), nil
+}
+
+// Returns the smallest set of prefixes covering everything in base that is not in exclude.
+func SubtractPrefixes(base []netip.Prefix, exclude []netip.Prefix) []netip.Prefix {
+	result := make([]netip.Prefix, 0, len(base))
+	for _, prefix := range base {
+		result = append(result, prefix.Masked())
+	}
+	for _, excluded := range exclude {
+		excluded = excluded.Masked()
+		var next []netip.Prefix
+		for _, prefix := range result {
+			next = append(next, subtractPrefix(prefix, excluded)...)
+		}
+		result = next
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if c := result[i].Addr().Compare(result[j].Addr()); c != 0 {
+			return c < 0
+		}
+		return result[i].Bits() < result[j].Bits()
+	})
+	return result
+}
+
+func subtractPrefix(prefix netip.Prefix, excluded netip.Prefix) []netip.Prefix {
+	if !prefix.Overlaps(excluded) {
+		return []netip.Prefix{prefix}
+	}
+	if excluded.Bits() <= prefix.Bits() {
+		// excluded covers the whole prefix
+		return nil
+	}
+	low, high := splitPrefix(prefix)
+	if low.Contains(excluded.Addr()) {
+		return append(subtractPrefix(low, excluded), high)
+	}
+	return append([]netip.Prefix{low}, subtractPrefix(high, excluded)...)
+}
+
+// Splits a prefix into its two halves.
+func splitPrefix(prefix netip.Prefix) (netip.Prefix, netip.Prefix) {
+	bits := prefix.Bits() + 1
+	low := netip.PrefixFrom(prefix.Addr(), bits)
+	highBytes := prefix.Addr().AsSlice()
+	highBytes[prefix.Bits()/8] |= 0x80 >> (prefix.Bits() % 8)
+	highAddr, _ := netip.AddrFromSlice(highBytes)
+	high := netip.PrefixFrom(highAddr, bits)
+	return low, high
+}
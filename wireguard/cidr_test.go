@@ -0,0 +1,64 @@
+package wireguard
+
+import (
+	"net/netip"
+	"testing"
+)
+
+func parsePrefixes(t *testing.T, values ...string) []netip.Prefix {
+	var prefixes []netip.Prefix
+	for _, value := range values {
+		prefix, err := ParsePrefix(value)
+		if err != nil {
+			t.Fatal(err)
+		}
+		prefixes = append(prefixes, prefix)
+	}
+	return prefixes
+}
+
+func assertPrefixes(t *testing.T, expected []string, result []netip.Prefix) {
+	if len(expected) != len(result) {
+		t.Fatalf("expected %v, got %v", expected, result)
+	}
+	for i := range expected {
+		if expected[i] != result[i].String() {
+			t.Fatalf("expected %v, got %v", expected, result)
+		}
+	}
+}
+
+func TestSubtractPrefixesIPv4(t *testing.T) {
+	result := SubtractPrefixes(parsePrefixes(t, "0.0.0.0/0"), parsePrefixes(t, "10.0.0.0/8", "192.168.0.0/16"))
+	assertPrefixes(t, []string{
+		"0.0.0.0/5", "8.0.0.0/7", "11.0.0.0/8", "12.0.0.0/6", "16.0.0.0/4", "32.0.0.0/3", "64.0.0.0/2",
+		"128.0.0.0/2", "192.0.0.0/9", "192.128.0.0/11", "192.160.0.0/13", "192.169.0.0/16",
+		"192.170.0.0/15", "192.172.0.0/14", "192.176.0.0/12", "192.192.0.0/10", "193.0.0.0/8",
+		"194.0.0.0/7", "196.0.0.0/6", "200.0.0.0/5", "208.0.0.0/4", "224.0.0.0/3",
+	}, result)
+}
+
+func TestSubtractPrefixesIPv6(t *testing.T) {
+	result := SubtractPrefixes(parsePrefixes(t, "::/0"), parsePrefixes(t, "fc00::/7"))
+	assertPrefixes(t, []string{
+		"::/1", "8000::/2", "c000::/3", "e000::/4", "f000::/5", "f800::/6", "fe00::/7",
+	}, result)
+
+	result = SubtractPrefixes(parsePrefixes(t, "2001:db8::/126"), parsePrefixes(t, "2001:db8::1"))
+	assertPrefixes(t, []string{"2001:db8::/128", "2001:db8::2/127"}, result)
+}
+
+func TestSubtractPrefixesMixedFamilies(t *testing.T) {
+	result := SubtractPrefixes(parsePrefixes(t, "0.0.0.0/0", "::/0"), parsePrefixes(t, "0.0.0.0/1", "::/1"))
+	assertPrefixes(t, []string{"128.0.0.0/1", "8000::/1"}, result)
+}
+
+func TestSubtractPrefixesCoveringExclusion(t *testing.T) {
+	result := SubtractPrefixes(parsePrefixes(t, "10.1.0.0/16"), parsePrefixes(t, "10.0.0.0/8"))
+	assertPrefixes(t, []string{}, result)
+}
+
+func TestParsePrefixSingleAddress(t *testing.T) {
+	assertPrefixes(t, []string{"1.2.3.4/32", "2606:4700::1/128", "10.0.0.0/8"},
+		parsePrefixes(t, "1.2.3.4", "2606:4700::1", "10.1.2.3/8"))
+}
@@ -0,0 +1,85 @@
+package wireguard
+
+import (
+	"net"
+	"net/netip"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Which address the profile uses to reach the peer.
+const (
+	// the host name, which requires DNS
+	EndpointModeHost = "host"
+	EndpointModeV4   = "v4"
+	EndpointModeV6   = "v6"
+	// the first address family this host has a route for, preferring IPv4
+	EndpointModeAuto = "auto"
+)
+
+var EndpointModes = []string{EndpointModeHost, EndpointModeV4, EndpointModeV6, EndpointModeAuto}
+
+// Checks whether the system has a route to the address. Connecting a UDP socket sends nothing.
+var hasRoute = func(addr netip.Addr) bool {
+	c, err := net.DialUDP("udp", nil, net.UDPAddrFromAddrPort(netip.AddrPortFrom(addr, 53)))
+	if err != nil {
+		return false
+	}
+	_ = c.Close()
+	return true
+}
+
+// Returns the endpoint for the mode. The literal addresses use the port of the host endpoint,
+// as the API returns them with port 0.
+func (data *ProfileData) GetEndpointForMode(mode string) (string, error) {
+	switch mode {
+	case "", EndpointModeHost:
+		return data.Endpoint, nil
+	case EndpointModeV4:
+		return data.getLiteralEndpoint(data.EndpointV4, "IPv4")
+	case EndpointModeV6:
+		return data.getLiteralEndpoint(data.EndpointV6, "IPv6")
+	case EndpointModeAuto:
+		for _, endpoint := range []string{data.EndpointV4, data.EndpointV6} {
+			if addrPort, err := netip.ParseAddrPort(endpoint); err == nil && hasRoute(addrPort.Addr()) {
+				return data.getLiteralEndpoint(endpoint, "")
+			}
+		}
+		return data.Endpoint, nil
+	default:
+		return "", errors.Errorf("invalid endpoint mode %s, must be one of: %s", mode, strings.Join(EndpointModes, ", "))
+	}
+}
+
+func (data *ProfileData) getLiteralEndpoint(endpoint string, family string) (string, error) {
+	addrPort, err := netip.ParseAddrPort(endpoint)
+	if err != nil {
+		return "", errors.Errorf("the device has no %s endpoint", family)
+	}
+	port := data.GetEndpointPort()
+	if port == "" {
+		return "", errors.New("invalid endpoint " + data.Endpoint)
+	}
+	return net.JoinHostPort(addrPort.Addr().String(), port), nil
+}
+
+// Returns the endpoint if it's an address, otherwise the address of the IP mode's family, preferring IPv4.
+func (data *ProfileData) getLiteralEndpointAddrPort() (netip.AddrPort, error) {
+	if addrPort, err := netip.ParseAddrPort(data.Endpoint); err == nil {
+		return addrPort, nil
+	}
+	var modes []string
+	if data.hasIPv4() {
+		modes = append(modes, EndpointModeV4)
+	}
+	if data.hasIPv6() {
+		modes = append(modes, EndpointModeV6)
+	}
+	for _, mode := range modes {
+		if endpoint, err := data.GetEndpointForMode(mode); err == nil {
+			return netip.ParseAddrPort(endpoint)
+		}
+	}
+	return netip.AddrPort{}, errors.Errorf("the device has no endpoint address for %s", data.Endpoint)
+}
@@ -0,0 +1,43 @@
+package wireguard
+
+import (
+	"net/netip"
+	"testing"
+)
+
+func TestGetEndpointForMode(t *testing.T) {
+	data := testProfileData
+	data.EndpointV4 = "162.159.192.1:0"
+	data.EndpointV6 = "[2606:4700:d0::a29f:c001]:0"
+
+	defer func(original func(netip.Addr) bool) { hasRoute = original }(hasRoute)
+	ipv6Only := func(addr netip.Addr) bool { return addr.Is6() }
+	noRoute := func(addr netip.Addr) bool { return false }
+
+	for _, test := range []struct {
+		mode     string
+		route    func(netip.Addr) bool
+		expected string
+	}{
+		{"", nil, "engage.cloudflareclient.com:2408"},
+		{EndpointModeHost, nil, "engage.cloudflareclient.com:2408"},
+		{EndpointModeV4, nil, "162.159.192.1:2408"},
+		{EndpointModeV6, nil, "[2606:4700:d0::a29f:c001]:2408"},
+		{EndpointModeAuto, ipv6Only, "[2606:4700:d0::a29f:c001]:2408"},
+		{EndpointModeAuto, noRoute, "engage.cloudflareclient.com:2408"},
+	} {
+		hasRoute = test.route
+		endpoint, err := data.GetEndpointForMode(test.mode)
+		if err != nil || endpoint != test.expected {
+			t.Errorf("mode %q: expected %s, got %s: %v", test.mode, test.expected, endpoint, err)
+		}
+	}
+
+	data.EndpointV6 = ""
+	if _, err := data.GetEndpointForMode(EndpointModeV6); err == nil {
+		t.Error("expected error without IPv6 endpoint")
+	}
+	if _, err := data.GetEndpointForMode("dns"); err == nil {
+		t.Error("expected error for invalid mode")
+	}
+}
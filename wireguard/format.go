@@ -0,0 +1,134 @@
+package wireguard
+
+import (
+	"encoding/json"
+	"net/netip"
+	"sort"
+)
+
+// A file of a rendered profile.
+type ProfileFile struct {
+	// the default extension of the file, e.g. .conf
+	Extension string
+	Content   string
+}
+
+// Renders a profile in the configuration format of a WireGuard implementation.
+type Formatter interface {
+	// Returns one or more files, e.g. a systemd-networkd .netdev and .network pair.
+	Format(data *ProfileData) ([]ProfileFile, error)
+}
+
+const DefaultFormat = "wg-quick"
+
+// Name of the interface in the formats which need one.
+const InterfaceName = "wgcf"
+
+var Formatters = map[string]Formatter{
+	"wg-quick":       &templateFormatter{templates: []fileTemplate{{".conf", wgQuickTemplate}}},
+	"wg":             &templateFormatter{templates: []fileTemplate{{".conf", wgTemplate}}},
+	"networkd":       &templateFormatter{templates: []fileTemplate{{".netdev", networkdNetdevTemplate}, {".network", networkdNetworkTemplate}}, endpoint: true},
+	"networkmanager": &templateFormatter{templates: []fileTemplate{{".nmconnection", networkManagerTemplate}}, endpoint: true},
+	"openwrt":        &templateFormatter{templates: []fileTemplate{{".uci", openWrtTemplate}}, endpoint: true},
+	"mikrotik":       &templateFormatter{templates: []fileTemplate{{".rsc", mikrotikTemplate}}, endpoint: true},
+	"sing-box":       &jsonFormatter{outbound: singBoxEndpoint},
+	"xray":           &jsonFormatter{outbound: xrayOutbound},
+	"wireguard-go":   &uapiFormatter{},
+}
+
+func GetFormatNames() []string {
+	var names []string
+	for name := range Formatters {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Returns the default extension of a format, e.g. .conf.
+func GetFormatExtension(formatter Formatter) string {
+	switch formatter := formatter.(type) {
+	case *templateFormatter:
+		return formatter.templates[0].extension
+	case *jsonFormatter:
+		return ".json"
+	case *uapiFormatter:
+		return ".uapi"
+	}
+	return ""
+}
+
+type fileTemplate struct {
+	extension string
+	text      string
+}
+
+type templateFormatter struct {
+	templates []fileTemplate
+	// whether the endpoint must be split into host and port
+	endpoint bool
+}
+
+func (f *templateFormatter) Format(data *ProfileData) ([]ProfileFile, error) {
+	if f.endpoint {
+		if err := data.validateEndpoint(); err != nil {
+			return nil, err
+		}
+	}
+	var files []ProfileFile
+	for _, fileTemplate := range f.templates {
+		content, err := executeTemplate(fileTemplate.text, data)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, ProfileFile{Extension: fileTemplate.extension, Content: content})
+	}
+	return files, nil
+}
+
+type jsonFormatter struct {
+	outbound func(data *ProfileData) (interface{}, error)
+}
+
+func (f *jsonFormatter) Format(data *ProfileData) ([]ProfileFile, error) {
+	if err := data.validateEndpoint(); err != nil {
+		return nil, err
+	}
+	outbound, err := f.outbound(data)
+	if err != nil {
+		return nil, err
+	}
+	content, err := json.MarshalIndent(outbound, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return []ProfileFile{{Extension: ".json", Content: string(content) + "\n"}}, nil
+}
+
+// wireguard-go has no configuration file, it's configured over UAPI, e.g. with device.IpcSet.
+// The interface addresses, MTU and DNS are up to the program which creates the device.
+type uapiFormatter struct{}
+
+func (f *uapiFormatter) Format(data *ProfileData) ([]ProfileFile, error) {
+	if err := data.validateEndpoint(); err != nil {
+		return nil, err
+	}
+	// UAPI doesn't resolve host names
+	endpoint, err := data.getLiteralEndpointAddrPort()
+	if err != nil {
+		return nil, err
+	}
+	content, err := formatIpcConfig(data, endpoint, data.GetFwMark())
+	if err != nil {
+		return nil, err
+	}
+	return []ProfileFile{{Extension: ".uapi", Content: content}}, nil
+}
+
+func parsePort(port string) (uint16, error) {
+	addrPort, err := netip.ParseAddrPort("0.0.0.0:" + port)
+	if err != nil {
+		return 0, err
+	}
+	return addrPort.Port(), nil
+}
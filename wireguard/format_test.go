@@ -0,0 +1,141 @@
+package wireguard
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+var updateGolden = flag.Bool("update", false, "update the golden files")
+
+var testProfileData = ProfileData{
+	PrivateKey: "yAnz5TF+lXXJte14tji3zlMNq+hd2rYUIgJBgB3fBmk=",
+	Address1:   "172.16.0.2",
+	Address2:   "2606:4700:110:8a36:df92:102a:9602:fa18",
+	PublicKey:  "bmXOC+F1FxEMF9dyiK2H5/1SUtzH0JuVo51h2wPfgyo=",
+	Endpoint:   "engage.cloudflareclient.com:2408",
+	EndpointV4: "162.159.192.1:0",
+	EndpointV6: "[2606:4700:d0::a29f:c001]:0",
+}
+
+// every customizable field set, in IPv4-only mode
+var testCustomProfileData = ProfileData{
+	PrivateKey:          testProfileData.PrivateKey,
+	Address1:            testProfileData.Address1,
+	Address2:            testProfileData.Address2,
+	PublicKey:           testProfileData.PublicKey,
+	Endpoint:            testProfileData.Endpoint,
+	EndpointV4:          testProfileData.EndpointV4,
+	EndpointV6:          testProfileData.EndpointV6,
+	ClientId:            "AQID",
+	AllowedIPs:          []string{"10.0.0.0/8", "192.168.0.0/16"},
+	MTU:                 1420,
+	DNS:                 []string{"9.9.9.9"},
+	PersistentKeepalive: 25,
+	Table:               "off",
+	FwMark:              "0xca6c",
+	PreUp:               []string{"echo pre-up"},
+	PostUp:              []string{"ip rule add table 200", "echo post-up"},
+	PreDown:             []string{"echo pre-down"},
+	PostDown:            []string{"ip rule del table 200"},
+	IPMode:              IPModeV4,
+}
+
+func TestFormatGolden(t *testing.T) {
+	testFormatGolden(t, testProfileData, "")
+}
+
+func TestFormatCustomGolden(t *testing.T) {
+	testFormatGolden(t, testCustomProfileData, "-custom")
+}
+
+func testFormatGolden(t *testing.T, profileData ProfileData, suffix string) {
+	for _, name := range GetFormatNames() {
+		t.Run(name, func(t *testing.T) {
+			data := profileData
+			files, err := Formatters[name].Format(&data)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, file := range files {
+				goldenFile := filepath.Join("testdata", name+suffix+file.Extension+".golden")
+				if *updateGolden {
+					if err := os.WriteFile(goldenFile, []byte(file.Content), 0644); err != nil {
+						t.Fatal(err)
+					}
+				}
+				expected, err := os.ReadFile(goldenFile)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if string(expected) != file.Content {
+					t.Fatalf("%s does not match, got:\n%s", goldenFile, file.Content)
+				}
+			}
+		})
+	}
+}
+
+func TestFormatInvalidEndpoint(t *testing.T) {
+	data := testProfileData
+	data.Endpoint = "engage.cloudflareclient.com"
+	for _, name := range []string{"openwrt", "mikrotik", "sing-box", "xray", "wireguard-go"} {
+		if _, err := Formatters[name].Format(&data); err == nil {
+			t.Fatalf("%s: expected invalid endpoint error", name)
+		}
+	}
+}
+
+func TestProfilePaths(t *testing.T) {
+	data := testProfileData
+	profile, err := NewFormattedProfile(&data, Formatters["networkd"])
+	if err != nil {
+		t.Fatal(err)
+	}
+	paths := profile.GetPaths("/etc/systemd/network/wgcf.conf")
+	if len(paths) != 2 || paths[0] != "/etc/systemd/network/wgcf.netdev" || paths[1] != "/etc/systemd/network/wgcf.network" {
+		t.Fatalf("unexpected paths %v", paths)
+	}
+
+	profile, err = NewProfile(&data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if paths := profile.GetPaths("wgcf-profile.txt"); len(paths) != 1 || paths[0] != "wgcf-profile.txt" {
+		t.Fatalf("unexpected paths %v", paths)
+	}
+}
+
+func TestValidateProfileData(t *testing.T) {
+	for _, invalid := range []func(data *ProfileData){
+		func(data *ProfileData) { data.IPMode = "v5" },
+		func(data *ProfileData) { data.MTU = 1000 },
+		func(data *ProfileData) { data.MTU = 70000 },
+		func(data *ProfileData) { data.DNS = []string{"one.one.one.one"} },
+		func(data *ProfileData) { data.IPMode = IPModeV4; data.DNS = []string{"2606:4700:4700::1111"} },
+		func(data *ProfileData) { data.AllowedIPs = []string{"10.0.0.0/33"} },
+		func(data *ProfileData) { data.IPMode = IPModeV6; data.AllowedIPs = []string{"10.0.0.0/8"} },
+		func(data *ProfileData) { data.PersistentKeepalive = -1 },
+		func(data *ProfileData) { data.Table = "main table" },
+		func(data *ProfileData) { data.FwMark = "0x1ffffffff" },
+		func(data *ProfileData) { data.PostUp = []string{"echo a\nEndpoint = evil:1"} },
+	} {
+		data := testProfileData
+		invalid(&data)
+		if err := data.Validate(); err == nil {
+			t.Fatalf("expected %+v to be invalid", data)
+		}
+	}
+
+	data := testProfileData
+	data.IPMode = IPModeV4
+	data.MTU = 576
+	data.DNS = []string{}
+	if err := data.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	if dns := data.GetDNS(); len(dns) != 0 {
+		t.Fatalf("expected no DNS, got %v", dns)
+	}
+}
@@ -0,0 +1,255 @@
+package wireguard
+
+var wgQuickTemplate = `[Interface]
+PrivateKey = {{ .PrivateKey }}
+Address = {{ join .GetAddresses ", " }}
+{{- with .GetDNS }}
+DNS = {{ join . ", " }}
+{{- end }}
+MTU = {{ .GetMTU }}
+{{- with .Table }}
+Table = {{ . }}
+{{- end }}
+{{- with .GetFwMark }}
+FwMark = {{ . }}
+{{- end }}
+{{- range .PreUp }}
+PreUp = {{ . }}
+{{- end }}
+{{- range .PostUp }}
+PostUp = {{ . }}
+{{- end }}
+{{- range .PreDown }}
+PreDown = {{ . }}
+{{- end }}
+{{- range .PostDown }}
+PostDown = {{ . }}
+{{- end }}
+[Peer]
+PublicKey = {{ .PublicKey }}
+AllowedIPs = {{ join .GetAllowedIPs ", " }}
+Endpoint = {{ .Endpoint }}
+{{- with .PersistentKeepalive }}
+PersistentKeepalive = {{ . }}
+{{- end }}
+`
+
+// for formats without a field for the reserved bytes, so they are at hand for other implementations
+var reservedComment = `{{ with .GetReserved -}}
+# Reserved bytes, for implementations which support them: {{ json . }}
+{{ end }}`
+
+// for wg setconf, which doesn't support the wg-quick extensions such as Address and DNS
+var wgTemplate = `[Interface]
+PrivateKey = {{ .PrivateKey }}
+{{- with .GetFwMark }}
+FwMark = {{ . }}
+{{- end }}
+[Peer]
+PublicKey = {{ .PublicKey }}
+AllowedIPs = {{ join .GetAllowedIPs ", " }}
+Endpoint = {{ .Endpoint }}
+{{- with .PersistentKeepalive }}
+PersistentKeepalive = {{ . }}
+{{- end }}
+` + reservedComment
+
+var networkdNetdevTemplate = `[NetDev]
+Name=` + InterfaceName + `
+Kind=wireguard
+MTUBytes={{ .GetMTU }}
+
+[WireGuard]
+PrivateKey={{ .PrivateKey }}
+{{- with .GetFwMark }}
+FirewallMark={{ . }}
+{{- end }}
+
+[WireGuardPeer]
+PublicKey={{ .PublicKey }}
+{{- range .GetAllowedIPs }}
+AllowedIPs={{ . }}
+{{- end }}
+Endpoint={{ .Endpoint }}
+{{- with .PersistentKeepalive }}
+PersistentKeepalive={{ . }}
+{{- end }}
+` + reservedComment
+
+var networkdNetworkTemplate = `[Match]
+Name=` + InterfaceName + `
+
+[Network]
+{{- range .GetAddresses }}
+Address={{ . }}
+{{- end }}
+{{- range .GetDNS }}
+DNS={{ . }}
+{{- end }}
+`
+
+var networkManagerTemplate = `[connection]
+id=` + InterfaceName + `
+type=wireguard
+interface-name=` + InterfaceName + `
+
+[wireguard]
+mtu={{ .GetMTU }}
+private-key={{ .PrivateKey }}
+{{- with .GetFwMark }}
+fwmark={{ . }}
+{{- end }}
+
+[wireguard-peer.{{ .PublicKey }}]
+endpoint={{ .Endpoint }}
+allowed-ips={{ join .GetAllowedIPs ";" }};
+{{- with .PersistentKeepalive }}
+persistent-keepalive={{ . }}
+{{- end }}
+` + reservedComment + `
+[ipv4]
+{{- with ipv4 .GetAddresses }}
+method=manual
+address1={{ index . 0 }}
+{{- else }}
+method=disabled
+{{- end }}
+{{- with ipv4 .GetDNS }}
+dns={{ join . ";" }};
+{{- end }}
+
+[ipv6]
+{{- with ipv6 .GetAddresses }}
+method=manual
+address1={{ index . 0 }}
+{{- else }}
+method=disabled
+{{- end }}
+{{- with ipv6 .GetDNS }}
+dns={{ join . ";" }};
+{{- end }}
+`
+
+// for /etc/config/network
+var openWrtTemplate = `config interface '` + InterfaceName + `'
+	option proto 'wireguard'
+	option private_key '{{ .PrivateKey }}'
+	option mtu '{{ .GetMTU }}'
+{{- with .GetFwMark }}
+	option fwmark '{{ . }}'
+{{- end }}
+{{- range .GetAddresses }}
+	list addresses '{{ . }}'
+{{- end }}
+{{- range .GetDNS }}
+	list dns '{{ . }}'
+{{- end }}
+
+config wireguard_` + InterfaceName + `
+	option description 'Cloudflare Warp'
+	option public_key '{{ .PublicKey }}'
+	option endpoint_host '{{ .GetEndpointHost }}'
+	option endpoint_port '{{ .GetEndpointPort }}'
+	option route_allowed_ips '1'
+{{- with .PersistentKeepalive }}
+	option persistent_keepalive '{{ . }}'
+{{- end }}
+{{- range .GetAllowedIPs }}
+	list allowed_ips '{{ . }}'
+{{- end }}
+` + reservedComment
+
+// for RouterOS v7, routes and DNS are left to the user as they affect the whole router
+var mikrotikTemplate = `/interface wireguard add name=` + InterfaceName + ` mtu={{ .GetMTU }} private-key="{{ .PrivateKey }}"
+/interface wireguard peers add interface=` + InterfaceName + ` public-key="{{ .PublicKey }}" endpoint-address={{ .GetEndpointHost }} endpoint-port={{ .GetEndpointPort }} allowed-address={{ join .GetAllowedIPs "," }}{{ with .PersistentKeepalive }} persistent-keepalive={{ . }}s{{ end }}
+{{- range ipv4 .GetAddresses }}
+/ip address add address={{ . }} interface=` + InterfaceName + `
+{{- end }}
+{{- range ipv6 .GetAddresses }}
+/ipv6 address add address={{ . }} interface=` + InterfaceName + ` advertise=no
+{{- end }}
+` + reservedComment
+
+// the WireGuard endpoint of sing-box 1.11 and later, which replaced the WireGuard outbound
+type singBoxWireGuardEndpoint struct {
+	Type       string                 `json:"type"`
+	Tag        string                 `json:"tag"`
+	MTU        int                    `json:"mtu"`
+	Address    []string               `json:"address"`
+	PrivateKey string                 `json:"private_key"`
+	Peers      []singBoxWireGuardPeer `json:"peers"`
+}
+
+type singBoxWireGuardPeer struct {
+	Address                     string   `json:"address"`
+	Port                        uint16   `json:"port"`
+	PublicKey                   string   `json:"public_key"`
+	AllowedIPs                  []string `json:"allowed_ips"`
+	PersistentKeepaliveInterval int      `json:"persistent_keepalive_interval,omitempty"`
+	Reserved                    []int    `json:"reserved,omitempty"`
+}
+
+func singBoxEndpoint(data *ProfileData) (interface{}, error) {
+	port, err := parsePort(data.GetEndpointPort())
+	if err != nil {
+		return nil, err
+	}
+	return singBoxWireGuardEndpoint{
+		Type:       "wireguard",
+		Tag:        InterfaceName,
+		MTU:        data.GetMTU(),
+		Address:    data.GetAddresses(),
+		PrivateKey: data.PrivateKey,
+		Peers: []singBoxWireGuardPeer{{
+			Address:                     data.GetEndpointHost(),
+			Port:                        port,
+			PublicKey:                   data.PublicKey,
+			AllowedIPs:                  data.GetAllowedIPs(),
+			PersistentKeepaliveInterval: data.PersistentKeepalive,
+			Reserved:                    data.GetReserved(),
+		}},
+	}, nil
+}
+
+type xrayWireGuardOutbound struct {
+	Protocol string                `json:"protocol"`
+	Tag      string                `json:"tag"`
+	Settings xrayWireGuardSettings `json:"settings"`
+}
+
+type xrayWireGuardSettings struct {
+	SecretKey string              `json:"secretKey"`
+	Address   []string            `json:"address"`
+	Peers     []xrayWireGuardPeer `json:"peers"`
+	MTU       int                 `json:"mtu"`
+	Reserved  []int               `json:"reserved,omitempty"`
+}
+
+type xrayWireGuardPeer struct {
+	PublicKey  string   `json:"publicKey"`
+	AllowedIPs []string `json:"allowedIPs"`
+	Endpoint   string   `json:"endpoint"`
+	KeepAlive  int      `json:"keepAlive,omitempty"`
+}
+
+func xrayOutbound(data *ProfileData) (interface{}, error) {
+	if _, err := parsePort(data.GetEndpointPort()); err != nil {
+		return nil, err
+	}
+	return xrayWireGuardOutbound{
+		Protocol: "wireguard",
+		Tag:      InterfaceName,
+		Settings: xrayWireGuardSettings{
+			SecretKey: data.PrivateKey,
+			Address:   data.GetAddresses(),
+			Peers: []xrayWireGuardPeer{{
+				PublicKey:  data.PublicKey,
+				AllowedIPs: data.GetAllowedIPs(),
+				Endpoint:   data.Endpoint,
+				KeepAlive:  data.PersistentKeepalive,
+			}},
+			MTU:      data.GetMTU(),
+			Reserved: data.GetReserved(),
+		},
+	}, nil
+}
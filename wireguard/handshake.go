@@ -0,0 +1,150 @@
+package wireguard
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"encoding/binary"
+	"hash"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"golang.org/x/crypto/blake2s"
+	"golang.org/x/crypto/chacha20poly1305"
+	"golang.org/x/crypto/curve25519"
+)
+
+// See https://www.wireguard.com/protocol/
+const (
+	noiseConstruction = "Noise_IKpsk2_25519_ChaChaPoly_BLAKE2s"
+	noiseIdentifier   = "WireGuard v1 zx2c4 Jason@zx2c4.com"
+	labelMac1         = "mac1----"
+
+	messageInitiationType = 1
+	messageResponseType   = 2
+	messageInitiationSize = 148
+	messageResponseSize   = 92
+)
+
+var (
+	lastTimestamp      time.Time
+	lastTimestampMutex sync.Mutex
+)
+
+// Builds a handshake initiation message from the device to the peer, returning it with its sender index.
+// The reserved bytes, e.g. the decoded client id, are written after the message type.
+func NewHandshakeInitiation(privateKey *Key, peerPublicKey *Key, reserved []byte) ([]byte, uint32, error) {
+	if len(reserved) > 3 {
+		return nil, 0, errors.New("at most 3 reserved bytes are supported")
+	}
+	ephemeralKey, err := NewPrivateKey()
+	if err != nil {
+		return nil, 0, err
+	}
+	senderIndexBytes := make([]byte, 4)
+	if _, err := rand.Read(senderIndexBytes); err != nil {
+		return nil, 0, err
+	}
+	senderIndex := binary.LittleEndian.Uint32(senderIndexBytes)
+
+	message := make([]byte, messageInitiationSize)
+	message[0] = messageInitiationType
+	copy(message[1:4], reserved)
+	binary.LittleEndian.PutUint32(message[4:8], senderIndex)
+
+	chainKey := blake2s.Sum256([]byte(noiseConstruction))
+	handshakeHash := mixHash(chainKey, []byte(noiseIdentifier))
+	handshakeHash = mixHash(handshakeHash, peerPublicKey[:])
+
+	ephemeralPublicKey := ephemeralKey.Public()
+	copy(message[8:40], ephemeralPublicKey[:])
+	chainKey = kdf1(chainKey, ephemeralPublicKey[:])
+	handshakeHash = mixHash(handshakeHash, ephemeralPublicKey[:])
+
+	sharedSecret, err := curve25519.X25519(ephemeralKey[:], peerPublicKey[:])
+	if err != nil {
+		return nil, 0, err
+	}
+	chainKey, key := kdf2(chainKey, sharedSecret)
+	publicKey := privateKey.Public()
+	static := seal(key, publicKey[:], handshakeHash)
+	copy(message[40:88], static)
+	handshakeHash = mixHash(handshakeHash, static)
+
+	sharedSecret, err = curve25519.X25519(privateKey[:], peerPublicKey[:])
+	if err != nil {
+		return nil, 0, err
+	}
+	_, key = kdf2(chainKey, sharedSecret)
+	timestamp := seal(key, newTimestamp(), handshakeHash)
+	copy(message[88:116], timestamp)
+
+	mac1Key := blake2s.Sum256(append([]byte(labelMac1), peerPublicKey[:]...))
+	mac1, _ := blake2s.New128(mac1Key[:])
+	mac1.Write(message[:116])
+	copy(message[116:132], mac1.Sum(nil))
+	return message, senderIndex, nil
+}
+
+// Checks whether the message is a handshake response to the initiation with the sender index.
+// The reserved bytes of the message type are ignored, as they may be set by the peer.
+func IsHandshakeResponse(message []byte, senderIndex uint32) bool {
+	return len(message) == messageResponseSize && message[0] == messageResponseType &&
+		binary.LittleEndian.Uint32(message[8:12]) == senderIndex
+}
+
+// TAI64N, strictly increasing, as the peer drops initiations which aren't newer than the last one
+func newTimestamp() []byte {
+	lastTimestampMutex.Lock()
+	now := time.Now()
+	if !now.After(lastTimestamp) {
+		now = lastTimestamp.Add(time.Nanosecond)
+	}
+	lastTimestamp = now
+	lastTimestampMutex.Unlock()
+
+	timestamp := make([]byte, 12)
+	binary.BigEndian.PutUint64(timestamp[:8], uint64(0x400000000000000a+now.Unix()))
+	binary.BigEndian.PutUint32(timestamp[8:], uint32(now.Nanosecond()))
+	return timestamp
+}
+
+func mixHash(h [blake2s.Size]byte, data []byte) [blake2s.Size]byte {
+	return blake2s.Sum256(append(h[:], data...))
+}
+
+func newHMAC(key []byte) hash.Hash {
+	return hmac.New(func() hash.Hash {
+		h, _ := blake2s.New256(nil)
+		return h
+	}, key)
+}
+
+func hmacSum(key []byte, data ...[]byte) [blake2s.Size]byte {
+	mac := newHMAC(key)
+	for _, d := range data {
+		mac.Write(d)
+	}
+	var sum [blake2s.Size]byte
+	copy(sum[:], mac.Sum(nil))
+	return sum
+}
+
+func kdf1(chainKey [blake2s.Size]byte, input []byte) [blake2s.Size]byte {
+	t0 := hmacSum(chainKey[:], input)
+	return hmacSum(t0[:], []byte{1})
+}
+
+func kdf2(chainKey [blake2s.Size]byte, input []byte) ([blake2s.Size]byte, [blake2s.Size]byte) {
+	t0 := hmacSum(chainKey[:], input)
+	t1 := hmacSum(t0[:], []byte{1})
+	t2 := hmacSum(t0[:], t1[:], []byte{2})
+	return t1, t2
+}
+
+// with a zero nonce, as each key is only used once
+func seal(key [blake2s.Size]byte, plaintext []byte, additionalData [blake2s.Size]byte) []byte {
+	aead, _ := chacha20poly1305.New(key[:])
+	nonce := make([]byte, chacha20poly1305.NonceSize)
+	return aead.Seal(nil, nonce, plaintext, additionalData[:])
+}
@@ -1,54 +1,253 @@
 package wireguard
 
 import (
-	"bytes"
 	"io/ioutil"
-	"text/template"
+	"net"
+	"net/netip"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
 )
 
-var profileTemplate = `[Interface]
-PrivateKey = {{ .PrivateKey }}
-Address = {{ .Address1 }}/32, {{ .Address2 }}/128
-DNS = 1.1.1.1, 1.0.0.1, 2606:4700:4700::1111, 2606:4700:4700::1001
-MTU = 1280
-[Peer]
-PublicKey = {{ .PublicKey }}
-AllowedIPs = 0.0.0.0/0, ::/0
-Endpoint = {{ .Endpoint }}
-`
+// Same as the official Android app.
+var (
+	DefaultDNS = []string{"1.1.1.1", "1.0.0.1", "2606:4700:4700::1111", "2606:4700:4700::1001"}
+	DefaultMTU = 1280
+)
 
 type Profile struct {
-	profileString string
+	files []ProfileFile
 }
 
+// Which address families the profile uses.
+const (
+	IPModeDual = "dual"
+	IPModeV4   = "v4"
+	IPModeV6   = "v6"
+)
+
 type ProfileData struct {
 	PrivateKey string
-	Address1   string
-	Address2   string
-	PublicKey  string
-	Endpoint   string
+	// interface addresses, IPv4 and IPv6
+	Address1  string
+	Address2  string
+	PublicKey string
+	// host and port of the peer
+	Endpoint string
+	// IP and port of the peer, usually with port 0, e.g. 162.159.192.1:0
+	EndpointV4 string
+	EndpointV6 string
+	DeviceId   string
+	// e.g. free, limited or unlimited, empty when generated offline
+	AccountType string
+	// base64 of the reserved bytes used by Cloudflare to identify the device
+	ClientId string
+	// defaults to all addresses
+	AllowedIPs []string
+	// defaults to DefaultMTU
+	MTU int
+	// defaults to DefaultDNS, an empty non-nil slice means no DNS
+	DNS []string
+	// in seconds, 0 disables it
+	PersistentKeepalive int
+	// the routing table for wg-quick, e.g. off, auto or a table number
+	Table string
+	// e.g. off, 51820 or 0xca6c
+	FwMark string
+	// wg-quick hooks, run by the shell
+	PreUp    []string
+	PostUp   []string
+	PreDown  []string
+	PostDown []string
+	// defaults to IPModeDual
+	IPMode string
+}
+
+func (data *ProfileData) hasIPv4() bool {
+	return data.IPMode != IPModeV6
+}
+
+func (data *ProfileData) hasIPv6() bool {
+	return data.IPMode != IPModeV4
+}
+
+// Interface addresses in CIDR notation.
+func (data *ProfileData) GetAddresses() []string {
+	var addresses []string
+	if data.hasIPv4() {
+		addresses = append(addresses, data.Address1+"/32")
+	}
+	if data.hasIPv6() {
+		addresses = append(addresses, data.Address2+"/128")
+	}
+	return addresses
+}
+
+func (data *ProfileData) GetAllowedIPs() []string {
+	if len(data.AllowedIPs) > 0 {
+		return data.AllowedIPs
+	}
+	var allowedIPs []string
+	if data.hasIPv4() {
+		allowedIPs = append(allowedIPs, AllIPv4.String())
+	}
+	if data.hasIPv6() {
+		allowedIPs = append(allowedIPs, AllIPv6.String())
+	}
+	return allowedIPs
+}
+
+func (data *ProfileData) GetDNS() []string {
+	if data.DNS != nil {
+		return data.DNS
+	}
+	var dns []string
+	for _, server := range DefaultDNS {
+		if addr := netip.MustParseAddr(server); (addr.Is4() && data.hasIPv4()) || (addr.Is6() && data.hasIPv6()) {
+			dns = append(dns, server)
+		}
+	}
+	return dns
 }
 
+func (data *ProfileData) GetMTU() int {
+	if data.MTU == 0 {
+		return DefaultMTU
+	}
+	return data.MTU
+}
+
+// Returns the decoded client id, e.g. [1, 2, 3], or nil if there is none.
+func (data *ProfileData) GetReserved() []int {
+	reserved, err := DecodeClientId(data.ClientId)
+	if err != nil {
+		return nil
+	}
+	return reserved.Ints()
+}
+
+// Returns the fwmark in decimal, as not all formats accept hexadecimal, or "" if there is none.
+func (data *ProfileData) GetFwMark() string {
+	fwMark, err := strconv.ParseUint(data.FwMark, 0, 32)
+	if err != nil || fwMark == 0 {
+		return ""
+	}
+	return strconv.FormatUint(fwMark, 10)
+}
+
+// Checks the customizable fields, so an invalid profile is never written.
+func (data *ProfileData) Validate() error {
+	switch data.IPMode {
+	case "", IPModeDual, IPModeV4, IPModeV6:
+	default:
+		return errors.Errorf("invalid IP mode %s, must be one of: %s, %s, %s", data.IPMode, IPModeDual, IPModeV4, IPModeV6)
+	}
+	// IPv6 requires at least 1280
+	minMTU := 576
+	if data.hasIPv6() {
+		minMTU = 1280
+	}
+	if data.MTU != 0 && (data.MTU < minMTU || data.MTU > 65535) {
+		return errors.Errorf("invalid MTU %d, must be between %d and 65535", data.MTU, minMTU)
+	}
+	for _, server := range data.DNS {
+		addr, err := netip.ParseAddr(server)
+		if err != nil {
+			return errors.Errorf("invalid DNS server %s", server)
+		}
+		if (addr.Is4() && !data.hasIPv4()) || (addr.Is6() && !data.hasIPv6()) {
+			return errors.Errorf("DNS server %s does not match the IP mode %s", server, data.IPMode)
+		}
+	}
+	for _, allowedIP := range data.AllowedIPs {
+		prefix, err := ParsePrefix(allowedIP)
+		if err != nil {
+			return errors.WithMessage(err, "invalid allowed IPs")
+		}
+		if (prefix.Addr().Is4() && !data.hasIPv4()) || (prefix.Addr().Is6() && !data.hasIPv6()) {
+			return errors.Errorf("allowed IPs %s do not match the IP mode %s", allowedIP, data.IPMode)
+		}
+	}
+	if data.PersistentKeepalive < 0 || data.PersistentKeepalive > 65535 {
+		return errors.Errorf("invalid persistent keepalive %d, must be between 0 and 65535", data.PersistentKeepalive)
+	}
+	if data.Table != "" && data.Table != "off" && data.Table != "auto" {
+		if _, err := strconv.ParseUint(data.Table, 10, 32); err != nil {
+			return errors.Errorf("invalid table %s, must be off, auto or a table number", data.Table)
+		}
+	}
+	if data.FwMark != "" && data.FwMark != "off" {
+		if _, err := strconv.ParseUint(data.FwMark, 0, 32); err != nil {
+			return errors.Errorf("invalid fwmark %s, must be off or a 32-bit number", data.FwMark)
+		}
+	}
+	for _, hooks := range [][]string{data.PreUp, data.PostUp, data.PreDown, data.PostDown} {
+		for _, hook := range hooks {
+			if hook == "" || strings.ContainsAny(hook, "\r\n") {
+				return errors.Errorf("invalid hook %q, must be a single non-empty line", hook)
+			}
+		}
+	}
+	return nil
+}
+
+func (data *ProfileData) GetEndpointHost() string {
+	host, _, _ := net.SplitHostPort(data.Endpoint)
+	return host
+}
+
+func (data *ProfileData) GetEndpointPort() string {
+	_, port, _ := net.SplitHostPort(data.Endpoint)
+	return port
+}
+
+func (data *ProfileData) validateEndpoint() error {
+	if _, _, err := net.SplitHostPort(data.Endpoint); err != nil {
+		return errors.WithMessage(err, "invalid endpoint")
+	}
+	return nil
+}
+
+// Renders a wg-quick profile.
 func NewProfile(data *ProfileData) (*Profile, error) {
-	profileString, err := generateProfile(data)
+	return NewFormattedProfile(data, Formatters[DefaultFormat])
+}
+
+func NewFormattedProfile(data *ProfileData, formatter Formatter) (*Profile, error) {
+	if err := data.Validate(); err != nil {
+		return nil, err
+	}
+	files, err := formatter.Format(data)
 	if err != nil {
 		return nil, err
 	}
-	return &Profile{profileString: profileString}, nil
+	return &Profile{files: files}, nil
 }
 
-func generateProfile(data *ProfileData) (string, error) {
-	t, err := template.New("").Parse(profileTemplate)
-	if err != nil {
-		return "", err
+// Returns the paths the profile is saved to. A single file is saved to the profile file as is,
+// multiple files replace its extension with their own.
+func (p *Profile) GetPaths(profileFile string) []string {
+	if len(p.files) == 1 {
+		return []string{profileFile}
 	}
-	var result bytes.Buffer
-	if err := t.Execute(&result, data); err != nil {
-		return "", err
+	var paths []string
+	for _, file := range p.files {
+		paths = append(paths, strings.TrimSuffix(profileFile, filepath.Ext(profileFile))+file.Extension)
 	}
-	return result.String(), nil
+	return paths
 }
 
 func (p *Profile) Save(profileFile string) error {
-	return ioutil.WriteFile(profileFile, []byte(p.profileString), 0600)
+	for i, path := range p.GetPaths(profileFile) {
+		if err := ioutil.WriteFile(path, []byte(p.files[i].Content), 0600); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func generateProfile(data *ProfileData) (string, error) {
+	return executeTemplate(wgQuickTemplate, data)
 }
@@ -29,3 +29,32 @@ Endpoint = 5
 		t.Error()
 	}
 }
+
+func TestGenerateProfileAllowedIPs(t *testing.T) {
+	var expectedResult = `[Interface]
+PrivateKey = 1
+Address = 2/32, 3/128
+DNS = 1.1.1.1, 1.0.0.1, 2606:4700:4700::1111, 2606:4700:4700::1001
+MTU = 1280
+[Peer]
+PublicKey = 4
+AllowedIPs = 0.0.0.0/1, 128.0.0.0/1, ::/0
+Endpoint = 5
+`
+
+	result, err := generateProfile(&ProfileData{
+		PrivateKey: "1",
+		Address1:   "2",
+		Address2:   "3",
+		PublicKey:  "4",
+		Endpoint:   "5",
+		AllowedIPs: []string{"0.0.0.0/1", "128.0.0.0/1", "::/0"},
+	})
+	if err != nil {
+		t.Error(err)
+	}
+
+	if expectedResult != result {
+		t.Error()
+	}
+}
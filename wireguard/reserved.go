@@ -0,0 +1,41 @@
+package wireguard
+
+import (
+	"encoding/base64"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// The reserved bytes after the type of every WireGuard message, which Cloudflare uses to identify
+// the device. Implementations which don't set them may be dropped by some endpoints.
+type Reserved [3]byte
+
+// Decodes the client id of the device configuration, e.g. AQID to [1 2 3].
+func DecodeClientId(clientId string) (Reserved, error) {
+	var reserved Reserved
+	decoded, err := base64.StdEncoding.DecodeString(clientId)
+	if err != nil {
+		return reserved, errors.WithMessage(err, "invalid client id")
+	}
+	if len(decoded) != len(reserved) {
+		return reserved, errors.Errorf("invalid client id %s, must decode to %d bytes", clientId, len(reserved))
+	}
+	copy(reserved[:], decoded)
+	return reserved, nil
+}
+
+// The bytes as integers, e.g. [1 2 3], as sing-box and Xray expect them.
+func (r Reserved) Ints() []int {
+	return []int{int(r[0]), int(r[1]), int(r[2])}
+}
+
+// e.g. [1, 2, 3]
+func (r Reserved) String() string {
+	var values []string
+	for _, value := range r.Ints() {
+		values = append(values, strconv.Itoa(value))
+	}
+	return "[" + strings.Join(values, ", ") + "]"
+}
@@ -0,0 +1,22 @@
+package wireguard
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestDecodeClientId(t *testing.T) {
+	reserved, err := DecodeClientId("AQID")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reserved != (Reserved{1, 2, 3}) || !slices.Equal(reserved.Ints(), []int{1, 2, 3}) || reserved.String() != "[1, 2, 3]" {
+		t.Errorf("unexpected reserved bytes %s", reserved)
+	}
+
+	for _, clientId := range []string{"", "AQ==", "AQIDBA==", "not base64"} {
+		if _, err := DecodeClientId(clientId); err == nil {
+			t.Errorf("expected error for client id %q", clientId)
+		}
+	}
+}
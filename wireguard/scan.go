@@ -0,0 +1,160 @@
+package wireguard
+
+import (
+	"context"
+	"net"
+	"net/netip"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Ports Cloudflare Warp accepts WireGuard on, besides the default 2408.
+var EndpointPorts = []uint16{
+	2408, 500, 854, 859, 864, 878, 880, 890, 891, 894, 903, 908, 928, 934, 939, 942, 943, 945, 946,
+	955, 968, 987, 988, 1002, 1010, 1014, 1018, 1070, 1074, 1180, 1387, 1701, 1843, 2371, 2506, 3138,
+	3476, 3581, 3854, 4177, 4198, 4233, 4500, 5279, 5956, 7103, 7152, 7156, 7281, 7559, 8319, 8742,
+	8854, 8886,
+}
+
+// The peer rate limits initiations from the same device, e.g. wireguard-go to one per 20ms.
+const probeInterval = 100 * time.Millisecond
+
+var DefaultScanOptions = ScanOptions{
+	Count:       3,
+	Timeout:     time.Second,
+	Concurrency: 32,
+}
+
+type ScanOptions struct {
+	PrivateKey    *Key
+	PeerPublicKey *Key
+	// written into every initiation, see ProfileData.GetReserved
+	Reserved []byte
+	// handshake initiations sent to each endpoint
+	Count int
+	// how long to wait for each response
+	Timeout time.Duration
+	// endpoints probed at the same time
+	Concurrency int
+}
+
+type ScanResult struct {
+	Endpoint netip.AddrPort
+	Sent     int
+	Received int
+	// average round-trip time of the received responses
+	RTT time.Duration
+}
+
+// Fraction of the initiations without a response, between 0 and 1.
+func (r *ScanResult) GetLoss() float64 {
+	if r.Sent == 0 {
+		return 1
+	}
+	return float64(r.Sent-r.Received) / float64(r.Sent)
+}
+
+// Returns every combination of the addresses and ports.
+func GetEndpointCandidates(addresses []netip.Addr, ports []uint16) []netip.AddrPort {
+	var candidates []netip.AddrPort
+	for _, address := range addresses {
+		for _, port := range ports {
+			candidates = append(candidates, netip.AddrPortFrom(address.Unmap(), port))
+		}
+	}
+	return candidates
+}
+
+// Probes the endpoints with handshake initiations, returning the results sorted from best to worst:
+// by loss, then by round-trip time. Endpoints which never responded are included at the end.
+func ScanEndpoints(ctx context.Context, endpoints []netip.AddrPort, options ScanOptions) []ScanResult {
+	if options.Concurrency < 1 {
+		options.Concurrency = 1
+	}
+	jobs := make(chan int)
+	results := make([]ScanResult, len(endpoints))
+	var wg sync.WaitGroup
+	for i := 0; i < options.Concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				results[i] = probeEndpoint(ctx, endpoints[i], &options)
+			}
+		}()
+	}
+	for i := range endpoints {
+		select {
+		case jobs <- i:
+		case <-ctx.Done():
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	sort.SliceStable(results, func(i, j int) bool {
+		if results[i].GetLoss() != results[j].GetLoss() {
+			return results[i].GetLoss() < results[j].GetLoss()
+		}
+		return results[i].RTT < results[j].RTT
+	})
+	return results
+}
+
+func probeEndpoint(ctx context.Context, endpoint netip.AddrPort, options *ScanOptions) ScanResult {
+	result := ScanResult{Endpoint: endpoint}
+	if ctx.Err() != nil {
+		return result
+	}
+	// unreachable networks, e.g. IPv6 on an IPv4-only host, count as loss
+	c, err := net.DialUDP("udp", nil, net.UDPAddrFromAddrPort(endpoint))
+	if err != nil {
+		result.Sent = options.Count
+		return result
+	}
+	defer c.Close()
+	// also stops waiting for a response
+	stop := context.AfterFunc(ctx, func() { _ = c.Close() })
+	defer stop()
+
+	var total time.Duration
+	buffer := make([]byte, messageResponseSize+1)
+probes:
+	for i := 0; i < options.Count && ctx.Err() == nil; i++ {
+		if i > 0 {
+			timer := time.NewTimer(probeInterval)
+			select {
+			case <-timer.C:
+			case <-ctx.Done():
+				timer.Stop()
+				break probes
+			}
+		}
+		result.Sent++
+		message, senderIndex, err := NewHandshakeInitiation(options.PrivateKey, options.PeerPublicKey, options.Reserved)
+		if err != nil {
+			continue
+		}
+		start := time.Now()
+		if _, err := c.Write(message); err != nil {
+			continue
+		}
+		_ = c.SetReadDeadline(start.Add(options.Timeout))
+		for {
+			n, err := c.Read(buffer)
+			if err != nil {
+				break
+			}
+			if IsHandshakeResponse(buffer[:n], senderIndex) {
+				result.Received++
+				total += time.Since(start)
+				break
+			}
+		}
+	}
+	if result.Received > 0 {
+		result.RTT = total / time.Duration(result.Received)
+	}
+	return result
+}
@@ -0,0 +1,135 @@
+package wireguard
+
+import (
+	"context"
+	"net"
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/ViRb3/wgcf/v2/wireguard/wgtest"
+)
+
+func TestScanEndpoints(t *testing.T) {
+	peer, err := wgtest.NewPeer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer peer.Close()
+	privateKey, _ := NewKey(peer.ClientPrivateKey)
+	peerPublicKey, _ := NewKey(peer.PublicKey)
+
+	// a silent endpoint, and one which responds with garbage
+	silent, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer silent.Close()
+	garbage, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer garbage.Close()
+	go func() {
+		buffer := make([]byte, 1500)
+		for {
+			_, from, err := garbage.ReadFromUDP(buffer)
+			if err != nil {
+				return
+			}
+			_, _ = garbage.WriteToUDP(make([]byte, messageResponseSize), from)
+		}
+	}()
+
+	endpoints := []netip.AddrPort{
+		silent.LocalAddr().(*net.UDPAddr).AddrPort(),
+		garbage.LocalAddr().(*net.UDPAddr).AddrPort(),
+		netip.MustParseAddrPort(peer.Endpoint),
+	}
+	results := ScanEndpoints(context.Background(), endpoints, ScanOptions{
+		PrivateKey:    privateKey,
+		PeerPublicKey: peerPublicKey,
+		Count:         3,
+		Timeout:       200 * time.Millisecond,
+		Concurrency:   2,
+	})
+	if len(results) != len(endpoints) {
+		t.Fatalf("expected %d results, got %d", len(endpoints), len(results))
+	}
+	best := results[0]
+	if best.Endpoint != endpoints[2] || best.Sent != 3 || best.Received != 3 || best.RTT <= 0 || best.GetLoss() != 0 {
+		t.Fatalf("unexpected best result %+v", best)
+	}
+	for _, result := range results[1:] {
+		if result.Received != 0 || result.GetLoss() != 1 {
+			t.Fatalf("unexpected result %+v", result)
+		}
+	}
+}
+
+func TestScanEndpointsWrongKey(t *testing.T) {
+	peer, err := wgtest.NewPeer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer peer.Close()
+	// the peer doesn't know this device
+	privateKey, _ := NewPrivateKey()
+	peerPublicKey, _ := NewKey(peer.PublicKey)
+
+	results := ScanEndpoints(context.Background(), []netip.AddrPort{netip.MustParseAddrPort(peer.Endpoint)}, ScanOptions{
+		PrivateKey:    privateKey,
+		PeerPublicKey: peerPublicKey,
+		Count:         1,
+		Timeout:       200 * time.Millisecond,
+		Concurrency:   1,
+	})
+	if results[0].Received != 0 {
+		t.Fatalf("unexpected result %+v", results[0])
+	}
+}
+
+func TestScanEndpointsCancel(t *testing.T) {
+	peer, err := wgtest.NewPeer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer peer.Close()
+	privateKey, _ := NewKey(peer.ClientPrivateKey)
+	peerPublicKey, _ := NewKey(peer.PublicKey)
+	silent, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer silent.Close()
+
+	// probing both endpoints in full would take 10 seconds
+	ctx, cancel := context.WithTimeout(context.Background(), 300*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	ScanEndpoints(ctx, []netip.AddrPort{netip.MustParseAddrPort(peer.Endpoint), silent.LocalAddr().(*net.UDPAddr).AddrPort()}, ScanOptions{
+		PrivateKey:    privateKey,
+		PeerPublicKey: peerPublicKey,
+		Count:         100,
+		Timeout:       5 * time.Second,
+		Concurrency:   2,
+	})
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("scan took %v after being cancelled", elapsed)
+	}
+}
+
+func TestGetEndpointCandidates(t *testing.T) {
+	candidates := GetEndpointCandidates(
+		[]netip.Addr{netip.MustParseAddr("162.159.192.1"), netip.MustParseAddr("2606:4700:d0::a29f:c001")},
+		[]uint16{2408, 500})
+	expected := []string{"162.159.192.1:2408", "162.159.192.1:500", "[2606:4700:d0::a29f:c001]:2408", "[2606:4700:d0::a29f:c001]:500"}
+	if len(candidates) != len(expected) {
+		t.Fatalf("unexpected candidates %v", candidates)
+	}
+	for i := range expected {
+		if candidates[i].String() != expected[i] {
+			t.Fatalf("unexpected candidates %v", candidates)
+		}
+	}
+}
@@ -0,0 +1,103 @@
+package wireguard
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"strings"
+	"text/template"
+
+	"github.com/pkg/errors"
+)
+
+// Functions available to every template, including user templates.
+var templateFuncs = template.FuncMap{
+	"join":    strings.Join,
+	"split":   strings.Split,
+	"replace": strings.ReplaceAll,
+	"lower":   strings.ToLower,
+	"upper":   strings.ToUpper,
+	"trim":    strings.TrimSpace,
+	// keeps the addresses or networks of one family
+	"ipv4": func(addresses []string) []string { return filterAddresses(addresses, true) },
+	"ipv6": func(addresses []string) []string { return filterAddresses(addresses, false) },
+	// returns the value, or the fallback if the value is empty
+	"default": func(fallback interface{}, value interface{}) interface{} {
+		if value == nil || value == "" || value == 0 {
+			return fallback
+		}
+		return value
+	},
+	"json": func(value interface{}) (string, error) {
+		data, err := json.Marshal(value)
+		return string(data), err
+	},
+}
+
+// keeps the addresses or networks of one family
+func filterAddresses(addresses []string, ipv4 bool) []string {
+	var result []string
+	for _, address := range addresses {
+		prefix, err := ParsePrefix(address)
+		if err != nil {
+			continue
+		}
+		if prefix.Addr().Is4() == ipv4 {
+			result = append(result, address)
+		}
+	}
+	return result
+}
+
+func executeTemplate(text string, data *ProfileData) (string, error) {
+	t, err := template.New("").Funcs(templateFuncs).Parse(text)
+	if err != nil {
+		return "", err
+	}
+	var result bytes.Buffer
+	if err := t.Execute(&result, data); err != nil {
+		return "", err
+	}
+	return result.String(), nil
+}
+
+// Used to check user templates before contacting the API.
+var sampleProfileData = ProfileData{
+	PrivateKey:  "yAnz5TF+lXXJte14tji3zlMNq+hd2rYUIgJBgB3fBmk=",
+	Address1:    "172.16.0.2",
+	Address2:    "2606:4700:110:8000::2",
+	PublicKey:   "bmXOC+F1FxEMF9dyiK2H5/1SUtzH0JuVo51h2wPfgyo=",
+	Endpoint:    "engage.cloudflareclient.com:2408",
+	EndpointV4:  "162.159.192.1:0",
+	EndpointV6:  "[2606:4700:d0::a29f:c001]:0",
+	DeviceId:    "00000000-0000-0000-0000-000000000000",
+	AccountType: "free",
+	ClientId:    "AQID",
+}
+
+type userTemplateFormatter struct {
+	template *template.Template
+}
+
+// Parses a user template, which is rendered with ProfileData and the template functions.
+// The template is also rendered once with sample data, so mistakes such as unknown fields
+// are reported before anything is generated.
+func NewTemplateFormatter(text string) (Formatter, error) {
+	t, err := template.New("profile").Funcs(templateFuncs).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, errors.WithMessage(err, "invalid template")
+	}
+	sample := sampleProfileData
+	if err := t.Execute(io.Discard, &sample); err != nil {
+		return nil, errors.WithMessage(err, "invalid template")
+	}
+	return &userTemplateFormatter{template: t}, nil
+}
+
+func (f *userTemplateFormatter) Format(data *ProfileData) ([]ProfileFile, error) {
+	var result bytes.Buffer
+	if err := f.template.Execute(&result, data); err != nil {
+		return nil, err
+	}
+	return []ProfileFile{{Content: result.String()}}, nil
+}
@@ -0,0 +1,53 @@
+package wireguard
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestTemplateFormatter(t *testing.T) {
+	formatter, err := NewTemplateFormatter(`[Peer]
+PublicKey = {{ .PublicKey }}
+Endpoint = {{ .GetEndpointHost }}:{{ .GetEndpointPort }}
+Address = {{ join (ipv4 .GetAddresses) ", " }}
+Reserved = {{ .GetReserved | json }}
+Account = {{ .AccountType | default "free" | upper }}
+DNS = {{ .GetDNS | json }}
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := testProfileData
+	data.ClientId = "AQID"
+	files, err := formatter.Format(&data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 1 || files[0].Extension != "" {
+		t.Fatalf("expected a single file without extension, got %+v", files)
+	}
+	expected := `[Peer]
+PublicKey = bmXOC+F1FxEMF9dyiK2H5/1SUtzH0JuVo51h2wPfgyo=
+Endpoint = engage.cloudflareclient.com:2408
+Address = 172.16.0.2/32
+Reserved = [1,2,3]
+Account = FREE
+DNS = ["1.1.1.1","1.0.0.1","2606:4700:4700::1111","2606:4700:4700::1001"]
+`
+	if files[0].Content != expected {
+		t.Fatalf("unexpected profile:\n%s", files[0].Content)
+	}
+}
+
+func TestInvalidTemplate(t *testing.T) {
+	for _, text := range []string{
+		"{{ .PublicKey ",
+		"{{ .Unknown }}",
+		"{{ .GetAddresses | unknown }}",
+		"{{ index .GetReserved 5 }}",
+	} {
+		if _, err := NewTemplateFormatter(text); err == nil || !strings.Contains(err.Error(), "invalid template") {
+			t.Errorf("expected invalid template error for %q, got %v", text, err)
+		}
+	}
+}
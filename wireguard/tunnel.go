@@ -0,0 +1,223 @@
+package wireguard
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"net"
+	"net/netip"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"golang.zx2c4.com/wireguard/conn"
+	"golang.zx2c4.com/wireguard/device"
+	"golang.zx2c4.com/wireguard/tun"
+	"golang.zx2c4.com/wireguard/tun/netstack"
+)
+
+// Used to route the traffic of the tunnel itself around a default route, like wg-quick does.
+const DefaultFwMark = "51820"
+
+// A WireGuard device running inside this process.
+type Tunnel struct {
+	device *device.Device
+	name   string
+	// the userspace network stack, nil if a TUN interface is used
+	Net *netstack.Net
+	// undoes the interface configuration which isn't removed along with the interface
+	deconfigure func()
+}
+
+// Brings up the profile on a new TUN interface, and configures its addresses and routes.
+// Requires root or CAP_NET_ADMIN, and is only supported on Linux.
+func NewTUNTunnel(data *ProfileData, name string) (*Tunnel, error) {
+	if err := data.Validate(); err != nil {
+		return nil, err
+	}
+	tunDevice, err := tun.CreateTUN(name, data.GetMTU())
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed to create TUN interface")
+	}
+	if name, err = tunDevice.Name(); err != nil {
+		_ = tunDevice.Close()
+		return nil, err
+	}
+	fwMark := data.GetFwMark()
+	if fwMark == "" {
+		fwMark = DefaultFwMark
+	}
+	tunnel, err := newTunnel(data, tunDevice, name, fwMark)
+	if err != nil {
+		return nil, err
+	}
+	if tunnel.deconfigure, err = configureInterface(name, data, fwMark); err != nil {
+		tunnel.Close()
+		return nil, err
+	}
+	return tunnel, nil
+}
+
+// Brings up the profile on a userspace network stack, which is only reachable through Net.
+// Requires no privileges.
+func NewNetstackTunnel(data *ProfileData) (*Tunnel, error) {
+	if err := data.Validate(); err != nil {
+		return nil, err
+	}
+	var addresses []netip.Addr
+	for _, address := range data.GetAddresses() {
+		prefix, err := netip.ParsePrefix(address)
+		if err != nil {
+			return nil, errors.WithMessage(err, "invalid address")
+		}
+		addresses = append(addresses, prefix.Addr())
+	}
+	var dns []netip.Addr
+	for _, server := range data.GetDNS() {
+		dns = append(dns, netip.MustParseAddr(server))
+	}
+	tunDevice, tunNet, err := netstack.CreateNetTUN(addresses, dns, data.GetMTU())
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed to create userspace network stack")
+	}
+	tunnel, err := newTunnel(data, tunDevice, "netstack", data.GetFwMark())
+	if err != nil {
+		return nil, err
+	}
+	tunnel.Net = tunNet
+	return tunnel, nil
+}
+
+// takes ownership of the TUN device, closing it on failure
+func newTunnel(data *ProfileData, tunDevice tun.Device, name string, fwMark string) (*Tunnel, error) {
+	ipcConfig, err := getIpcConfig(data, fwMark)
+	if err != nil {
+		_ = tunDevice.Close()
+		return nil, err
+	}
+	logger := &device.Logger{
+		Verbosef: device.DiscardLogf,
+		Errorf: func(format string, args ...any) {
+			log.Printf("WireGuard: "+format, args...)
+		},
+	}
+	wgDevice := device.NewDevice(tunDevice, conn.NewDefaultBind(), logger)
+	if err := wgDevice.IpcSet(ipcConfig); err != nil {
+		wgDevice.Close()
+		return nil, errors.WithMessage(err, "failed to configure WireGuard device")
+	}
+	if err := wgDevice.Up(); err != nil {
+		wgDevice.Close()
+		return nil, errors.WithMessage(err, "failed to bring up WireGuard device")
+	}
+	return &Tunnel{device: wgDevice, name: name}, nil
+}
+
+// Returns the configuration of the device in the cross-platform userspace API format,
+// see https://www.wireguard.com/xplatform/
+func getIpcConfig(data *ProfileData, fwMark string) (string, error) {
+	// prefers IPv4, like wg-quick
+	endpoint, err := net.ResolveUDPAddr("udp", data.Endpoint)
+	if err != nil {
+		return "", errors.WithMessage(err, "failed to resolve endpoint")
+	}
+	return formatIpcConfig(data, endpoint.AddrPort(), fwMark)
+}
+
+// The body of a UAPI set operation, which configures wireguard-go.
+func formatIpcConfig(data *ProfileData, endpoint netip.AddrPort, fwMark string) (string, error) {
+	privateKey, err := parseKey(data.PrivateKey)
+	if err != nil {
+		return "", errors.WithMessage(err, "invalid private key")
+	}
+	publicKey, err := parseKey(data.PublicKey)
+	if err != nil {
+		return "", errors.WithMessage(err, "invalid public key")
+	}
+
+	var config strings.Builder
+	fmt.Fprintf(&config, "private_key=%s\n", hex.EncodeToString(privateKey[:]))
+	if fwMark != "" {
+		fmt.Fprintf(&config, "fwmark=%s\n", fwMark)
+	}
+	config.WriteString("replace_peers=true\n")
+	fmt.Fprintf(&config, "public_key=%s\n", hex.EncodeToString(publicKey[:]))
+	fmt.Fprintf(&config, "endpoint=%s\n", netip.AddrPortFrom(endpoint.Addr().Unmap(), endpoint.Port()))
+	fmt.Fprintf(&config, "persistent_keepalive_interval=%d\n", data.PersistentKeepalive)
+	config.WriteString("replace_allowed_ips=true\n")
+	for _, allowedIP := range data.GetAllowedIPs() {
+		fmt.Fprintf(&config, "allowed_ip=%s\n", allowedIP)
+	}
+	return config.String(), nil
+}
+
+func parseKey(base64Key string) (*Key, error) {
+	key, err := NewKey(base64Key)
+	if err != nil {
+		return nil, err
+	}
+	if key.IsZero() {
+		return nil, errors.New("empty key")
+	}
+	return key, nil
+}
+
+// The name of the TUN interface, or netstack.
+func (t *Tunnel) Name() string {
+	return t.name
+}
+
+// Returns the time of the last handshake with the peer, or the zero time if there was none.
+func (t *Tunnel) LastHandshake() (time.Time, error) {
+	config, err := t.device.IpcGet()
+	if err != nil {
+		return time.Time{}, err
+	}
+	var sec, nsec int64
+	for _, line := range strings.Split(config, "\n") {
+		key, value, _ := strings.Cut(line, "=")
+		switch key {
+		case "last_handshake_time_sec":
+			sec, err = strconv.ParseInt(value, 10, 64)
+		case "last_handshake_time_nsec":
+			nsec, err = strconv.ParseInt(value, 10, 64)
+		}
+		if err != nil {
+			return time.Time{}, err
+		}
+	}
+	if sec == 0 && nsec == 0 {
+		return time.Time{}, nil
+	}
+	return time.Unix(sec, nsec), nil
+}
+
+// Waits until the first handshake with the peer completes, or the context is done.
+func (t *Tunnel) WaitHandshake(ctx context.Context) error {
+	ticker := time.NewTicker(100 * time.Millisecond)
+	defer ticker.Stop()
+	for {
+		lastHandshake, err := t.LastHandshake()
+		if err != nil {
+			return err
+		}
+		if !lastHandshake.IsZero() {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// Brings down the tunnel and removes its interface.
+func (t *Tunnel) Close() {
+	if t.deconfigure != nil {
+		t.deconfigure()
+	}
+	t.device.Close()
+}
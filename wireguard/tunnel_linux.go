@@ -0,0 +1,146 @@
+package wireguard
+
+import (
+	"log"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Lets the replies to the tunnel's traffic pass the reverse path filter, as wg-quick does.
+const srcValidMarkFile = "/proc/sys/net/ipv4/conf/all/src_valid_mark"
+
+// Configures the interface like wg-quick. Default routes are added to a separate table, which
+// all traffic without the fwmark of the tunnel uses, so the endpoint is still reached directly.
+// Returns a function which undoes the configuration which outlives the interface, such as the routing rules.
+func configureInterface(name string, data *ProfileData, fwMark string) (func(), error) {
+	var undo []func()
+	deconfigure := func() {
+		for i := len(undo) - 1; i >= 0; i-- {
+			undo[i]()
+		}
+	}
+	fail := func(err error) (func(), error) {
+		deconfigure()
+		return nil, err
+	}
+
+	for _, address := range data.GetAddresses() {
+		if err := runIP("address", "add", address, "dev", name); err != nil {
+			return fail(err)
+		}
+	}
+	if err := runIP("link", "set", "mtu", strconv.Itoa(data.GetMTU()), "up", "dev", name); err != nil {
+		return fail(err)
+	}
+	if dns := data.GetDNS(); len(dns) > 0 {
+		restore, err := setDNS(name, dns)
+		if err != nil {
+			return fail(err)
+		}
+		undo = append(undo, restore)
+	}
+	if data.Table == "off" {
+		return deconfigure, nil
+	}
+
+	table := data.Table
+	if table == "" || table == "auto" {
+		table = fwMark
+	}
+	for _, allowedIP := range data.GetAllowedIPs() {
+		prefix, err := ParsePrefix(allowedIP)
+		if err != nil {
+			return fail(err)
+		}
+		family := "-6"
+		if prefix.Addr().Is4() {
+			family = "-4"
+		}
+		if data.Table != "" && data.Table != "auto" {
+			if err := runIP(family, "route", "add", prefix.String(), "dev", name, "table", table); err != nil {
+				return fail(err)
+			}
+			continue
+		}
+		if prefix.Bits() != 0 {
+			if err := runIP(family, "route", "add", prefix.String(), "dev", name); err != nil {
+				return fail(err)
+			}
+			continue
+		}
+		if err := runIP(family, "route", "add", prefix.String(), "dev", name, "table", table); err != nil {
+			return fail(err)
+		}
+		for _, rule := range [][]string{
+			{"not", "fwmark", fwMark, "table", table},
+			{"table", "main", "suppress_prefixlength", "0"},
+		} {
+			if err := runIP(append([]string{family, "rule", "add"}, rule...)...); err != nil {
+				return fail(err)
+			}
+			delRule := append([]string{family, "rule", "del"}, rule...)
+			undo = append(undo, func() { _ = runIP(delRule...) })
+		}
+		if prefix.Addr().Is4() {
+			restore, err := setSysctl(srcValidMarkFile, "1")
+			if err != nil {
+				return fail(err)
+			}
+			undo = append(undo, restore)
+		}
+	}
+	return deconfigure, nil
+}
+
+// Sets the DNS servers of the interface with resolvconf, which systemd-resolved also provides.
+// Returns a function which removes them.
+func setDNS(name string, dns []string) (func(), error) {
+	if _, err := exec.LookPath("resolvconf"); err != nil {
+		log.Println("Warning: resolvconf not found, DNS servers are not applied and DNS queries bypass the tunnel")
+		return func() {}, nil
+	}
+	var config strings.Builder
+	for _, server := range dns {
+		config.WriteString("nameserver " + server + "\n")
+	}
+	// the same interface name and options as wg-quick
+	command := exec.Command("resolvconf", "-a", "tun."+name, "-m", "0", "-x")
+	command.Stdin = strings.NewReader(config.String())
+	if output, err := command.CombinedOutput(); err != nil {
+		if len(output) > 0 {
+			return nil, errors.Errorf("resolvconf: %s", strings.TrimSpace(string(output)))
+		}
+		return nil, errors.WithMessage(err, "resolvconf")
+	}
+	return func() { _ = exec.Command("resolvconf", "-d", "tun."+name, "-f").Run() }, nil
+}
+
+// Returns a function which restores the previous value.
+func setSysctl(path string, value string) (func(), error) {
+	previous, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if strings.TrimSpace(string(previous)) == value {
+		return func() {}, nil
+	}
+	if err := os.WriteFile(path, []byte(value), 0644); err != nil {
+		return nil, errors.WithMessage(err, "failed to set "+path)
+	}
+	return func() { _ = os.WriteFile(path, previous, 0644) }, nil
+}
+
+func runIP(args ...string) error {
+	output, err := exec.Command("ip", args...).CombinedOutput()
+	if err != nil {
+		if len(output) > 0 {
+			return errors.Errorf("ip %s: %s", strings.Join(args, " "), strings.TrimSpace(string(output)))
+		}
+		return errors.WithMessage(err, "ip "+strings.Join(args, " "))
+	}
+	return nil
+}
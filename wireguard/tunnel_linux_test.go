@@ -0,0 +1,37 @@
+package wireguard
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSetSysctlRestores(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "src_valid_mark")
+	if err := os.WriteFile(path, []byte("0\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	restore, err := setSysctl(path, "1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if value, _ := os.ReadFile(path); string(value) != "1" {
+		t.Fatalf("expected 1, got %q", value)
+	}
+	restore()
+	if value, _ := os.ReadFile(path); string(value) != "0\n" {
+		t.Fatalf("expected the previous value, got %q", value)
+	}
+
+	// an already set value is left alone on restore
+	if err := os.WriteFile(path, []byte("1\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if restore, err = setSysctl(path, "1"); err != nil {
+		t.Fatal(err)
+	}
+	restore()
+	if value, _ := os.ReadFile(path); string(value) != "1\n" {
+		t.Fatalf("expected 1, got %q", value)
+	}
+}
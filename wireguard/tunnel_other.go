@@ -0,0 +1,9 @@
+//go:build !linux
+
+package wireguard
+
+import "github.com/pkg/errors"
+
+func configureInterface(name string, data *ProfileData, fwMark string) (func(), error) {
+	return nil, errors.New("configuring a TUN interface is only supported on Linux, use the userspace network stack instead")
+}
@@ -0,0 +1,121 @@
+package wireguard
+
+import (
+	"context"
+	"encoding/hex"
+	"io"
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/ViRb3/wgcf/v2/wireguard/wgtest"
+)
+
+func newTestTunnelData(t *testing.T) (*ProfileData, *wgtest.Peer) {
+	peer, err := wgtest.NewPeer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(peer.Close)
+	return &ProfileData{
+		PrivateKey:          peer.ClientPrivateKey,
+		Address1:            wgtest.ClientAddress.String(),
+		Address2:            "fd00::2",
+		PublicKey:           peer.PublicKey,
+		Endpoint:            peer.Endpoint,
+		PersistentKeepalive: 25,
+		IPMode:              IPModeV4,
+	}, peer
+}
+
+func TestNetstackTunnel(t *testing.T) {
+	data, peer := newTestTunnelData(t)
+	tunnel, err := NewNetstackTunnel(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tunnel.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := tunnel.WaitHandshake(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if lastHandshake, err := tunnel.LastHandshake(); err != nil || time.Since(lastHandshake) > time.Minute {
+		t.Fatalf("unexpected last handshake %v: %v", lastHandshake, err)
+	}
+
+	listener, err := peer.Net.ListenTCPAddrPort(netip.AddrPortFrom(wgtest.PeerAddress, 80))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	go func() {
+		c, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer c.Close()
+		_, _ = io.Copy(c, c)
+	}()
+	c, err := tunnel.Net.DialContextTCPAddrPort(ctx, netip.AddrPortFrom(wgtest.PeerAddress, 80))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	if _, err := c.Write([]byte("ping")); err != nil {
+		t.Fatal(err)
+	}
+	reply := make([]byte, 4)
+	if _, err := io.ReadFull(c, reply); err != nil || string(reply) != "ping" {
+		t.Fatalf("unexpected reply %q: %v", reply, err)
+	}
+}
+
+func TestTunnelNoHandshake(t *testing.T) {
+	data, _ := newTestTunnelData(t)
+	// nothing listens on the discard port
+	data.Endpoint = "127.0.0.1:9"
+	tunnel, err := NewNetstackTunnel(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tunnel.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+	if err := tunnel.WaitHandshake(ctx); err != context.DeadlineExceeded {
+		t.Fatalf("expected deadline exceeded, got %v", err)
+	}
+	if lastHandshake, err := tunnel.LastHandshake(); err != nil || !lastHandshake.IsZero() {
+		t.Fatalf("unexpected last handshake %v: %v", lastHandshake, err)
+	}
+}
+
+func TestIpcConfig(t *testing.T) {
+	data := testCustomProfileData
+	data.Endpoint = "162.159.192.1:2408"
+	config, err := getIpcConfig(&data, "51820")
+	if err != nil {
+		t.Fatal(err)
+	}
+	privateKey, _ := NewKey(data.PrivateKey)
+	publicKey, _ := NewKey(data.PublicKey)
+	expected := "private_key=" + hex.EncodeToString(privateKey[:]) + "\n" +
+		"fwmark=51820\n" +
+		"replace_peers=true\n" +
+		"public_key=" + hex.EncodeToString(publicKey[:]) + "\n" +
+		"endpoint=162.159.192.1:2408\n" +
+		"persistent_keepalive_interval=25\n" +
+		"replace_allowed_ips=true\n" +
+		"allowed_ip=10.0.0.0/8\n" +
+		"allowed_ip=192.168.0.0/16\n"
+	if config != expected {
+		t.Fatalf("unexpected config:\n%s", config)
+	}
+
+	data.PublicKey = ""
+	if _, err := getIpcConfig(&data, ""); err == nil {
+		t.Fatal("expected error for missing public key")
+	}
+}
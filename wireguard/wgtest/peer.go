@@ -0,0 +1,92 @@
+// Package wgtest provides an in-process WireGuard peer, standing in for Cloudflare in tests.
+package wgtest
+
+import (
+	"crypto/ecdh"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"net/netip"
+	"strings"
+
+	"github.com/pkg/errors"
+	"golang.zx2c4.com/wireguard/conn"
+	"golang.zx2c4.com/wireguard/device"
+	"golang.zx2c4.com/wireguard/tun/netstack"
+)
+
+// Addresses inside the tunnel.
+var (
+	PeerAddress   = netip.MustParseAddr("10.0.0.1")
+	ClientAddress = netip.MustParseAddr("10.0.0.2")
+)
+
+// A WireGuard peer listening on localhost, with a userspace network stack behind it.
+// It accepts a single client, whose keys are generated along with its own.
+type Peer struct {
+	device *device.Device
+	// the network of the peer, to serve the client through the tunnel
+	Net *netstack.Net
+	// base64 encoded
+	PublicKey        string
+	ClientPrivateKey string
+	// e.g. 127.0.0.1:51820
+	Endpoint string
+}
+
+func NewPeer() (*Peer, error) {
+	privateKey, err := ecdh.X25519().GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	clientPrivateKey, err := ecdh.X25519().GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	tunDevice, tunNet, err := netstack.CreateNetTUN([]netip.Addr{PeerAddress}, nil, 1280)
+	if err != nil {
+		return nil, err
+	}
+	peerDevice := device.NewDevice(tunDevice, conn.NewDefaultBind(), device.NewLogger(device.LogLevelError, "wgtest: "))
+	config := fmt.Sprintf("private_key=%s\nlisten_port=0\npublic_key=%s\nallowed_ip=%s/32\n",
+		hex.EncodeToString(privateKey.Bytes()), hex.EncodeToString(clientPrivateKey.PublicKey().Bytes()), ClientAddress)
+	if err := peerDevice.IpcSet(config); err != nil {
+		peerDevice.Close()
+		return nil, err
+	}
+	if err := peerDevice.Up(); err != nil {
+		peerDevice.Close()
+		return nil, err
+	}
+	port, err := getListenPort(peerDevice)
+	if err != nil {
+		peerDevice.Close()
+		return nil, err
+	}
+	return &Peer{
+		device:           peerDevice,
+		Net:              tunNet,
+		PublicKey:        base64.StdEncoding.EncodeToString(privateKey.PublicKey().Bytes()),
+		ClientPrivateKey: base64.StdEncoding.EncodeToString(clientPrivateKey.Bytes()),
+		Endpoint:         net.JoinHostPort("127.0.0.1", port),
+	}, nil
+}
+
+func getListenPort(peerDevice *device.Device) (string, error) {
+	config, err := peerDevice.IpcGet()
+	if err != nil {
+		return "", err
+	}
+	for _, line := range strings.Split(config, "\n") {
+		if port, ok := strings.CutPrefix(line, "listen_port="); ok {
+			return port, nil
+		}
+	}
+	return "", errors.New("no listen port")
+}
+
+func (p *Peer) Close() {
+	p.device.Close()
+}